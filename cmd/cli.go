@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/google/osv-scanner/pkg/models"
@@ -13,14 +15,38 @@ import (
 	sitter "github.com/smacker/go-tree-sitter"
 	treeSitterPy "github.com/smacker/go-tree-sitter/python"
 	"github.com/srijanpaul-deepsource/reachable/pkg/sniper"
+	"github.com/srijanpaul-deepsource/reachable/pkg/vulndb"
 )
 
 type Config struct {
 	Language     *sitter.Language
 	ProjectRoot  *string
 	LockfilePath string
-	ShowDotGraph		 bool
+	// OfflineDBPath is a local OSV dump to match the lockfile against
+	// instead of querying api.osv.dev
+	OfflineDBPath string
+	// PythonEnv configures where python imports are resolved from
+	PythonEnv sniper.PythonEnv
+	// Callbacks also follows functions that are passed as arguments to other functions
+	Callbacks    bool
+	ShowDotGraph bool
 	Files        []string
+	// Entrypoints are `module:function` entrypoints to start from, besides Files
+	Entrypoints []string
+	// FindEntrypoints also starts from the entrypoints declared or found in the repo root
+	FindEntrypoints bool
+}
+
+// stringList is a flag that can be given more than once
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ", ")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
 }
 
 func getTsLanguage(langName string) (*sitter.Language, error) {
@@ -37,6 +63,45 @@ func ReadConfig() (*Config, error) {
 	language := flag.String("language", "", "Programming language to be used")
 	lockFilePath := flag.String("lockfile", "", "Path to the lockfile")
 	showDotGraph := flag.Bool("dotgraph", false, "Show the call graph in dot format")
+	callbacks := flag.Bool(
+		"callbacks", false,
+		"Assume that functions passed as arguments (e.g: `Thread(target=f)`) are called by the callee",
+	)
+	offlineDBPath := flag.String(
+		"offline-db", "",
+		"Path to a local OSV database (an all.zip dump or a directory of OSV JSON files). "+
+			"When set, no network requests are made",
+	)
+
+	venvPath := flag.String(
+		"venv", "",
+		"Root of the python virtual environment to resolve imports from. "+
+			"Auto-detected from the project root when not set",
+	)
+	pythonPath := flag.String(
+		"python-path", "",
+		fmt.Sprintf(
+			"Extra directories to resolve python imports from, separated by '%c' (like PYTHONPATH)",
+			os.PathListSeparator,
+		),
+	)
+	typeshedPath := flag.String(
+		"typeshed", "",
+		"Path to a local checkout of typeshed, whose stubs describe modules without python source",
+	)
+
+	var entrypoints stringList
+	flag.Var(
+		&entrypoints, "entrypoint",
+		"An entrypoint to start from, as `module:function` (or `module`, to run the module as a script). "+
+			"Can be given more than once",
+	)
+	findEntrypoints := flag.Bool(
+		"find-entrypoints", false,
+		"Also start from the console scripts in setup.py, setup.cfg and pyproject.toml, and the "+
+			"__main__.py modules and `if __name__ == \"__main__\":` blocks in the repo root "+
+			"(or the current directory)",
+	)
 
 	flag.Parse()
 	files := flag.Args() // read positional args
@@ -59,11 +124,20 @@ func ReadConfig() (*Config, error) {
 	}
 
 	config := &Config{
-		Language:     tsLanguage,
-		ProjectRoot:  repoRoot,
-		LockfilePath: *lockFilePath,
-		Files:        files,
-		ShowDotGraph: *showDotGraph,
+		Language:      tsLanguage,
+		ProjectRoot:   repoRoot,
+		LockfilePath:  *lockFilePath,
+		OfflineDBPath: *offlineDBPath,
+		PythonEnv: sniper.PythonEnv{
+			VenvPath:     *venvPath,
+			ExtraPaths:   filepath.SplitList(*pythonPath),
+			TypeshedPath: *typeshedPath,
+		},
+		Callbacks:       *callbacks,
+		Files:           files,
+		Entrypoints:     entrypoints,
+		FindEntrypoints: *findEntrypoints,
+		ShowDotGraph:    *showDotGraph,
 	}
 
 	return config, nil
@@ -71,52 +145,136 @@ func ReadConfig() (*Config, error) {
 
 type Cli struct {
 	// language sitter.Language
-	files        []string
-	lockFilePath string
-	moduleCache  map[string]sniper.ParsedFile
-	showDotGraph bool
+	files           []string
+	entrypoints     []string
+	findEntrypoints bool
+	projectRoot     *string
+	lockFilePath    string
+	offlineDBPath   string
+	pythonEnv       sniper.PythonEnv
+	moduleCache     map[string]sniper.ParsedFile
+	callbacks       bool
+	showDotGraph    bool
+	// db is the offline OSV database, if one was given
+	db *vulndb.DB
 }
 
 func NewCli(conf *Config) *Cli {
 	return &Cli{
-		files:        conf.Files,
-		moduleCache:  make(map[string]sniper.ParsedFile),
-		lockFilePath: conf.LockfilePath,
-		showDotGraph: conf.ShowDotGraph,
+		files:           conf.Files,
+		entrypoints:     conf.Entrypoints,
+		findEntrypoints: conf.FindEntrypoints,
+		projectRoot:     conf.ProjectRoot,
+		moduleCache:     make(map[string]sniper.ParsedFile),
+		lockFilePath:    conf.LockfilePath,
+		offlineDBPath:   conf.OfflineDBPath,
+		pythonEnv:       conf.PythonEnv,
+		callbacks:       conf.Callbacks,
+		showDotGraph:    conf.ShowDotGraph,
 	}
 }
 
-type VulnDep struct {
-	packageName string
-	osvVulnId   string
-	osvVulnDesc string
-}
+// collectEntrypoints returns the entrypoints to start from: the files given as
+// arguments (which are run as scripts), the `--entrypoint`s, and with
+// `--find-entrypoints`, the entrypoints found in the repo root.
+func (c *Cli) collectEntrypoints() ([]sniper.Entrypoint, error) {
+	projectRoot := "."
+	if c.projectRoot != nil {
+		projectRoot = *c.projectRoot
+	}
 
-func collectVulnerableDepNames(report models.VulnerabilityResults) map[string]VulnDep {
-	depNames := make(map[string]VulnDep)
-	for _, result := range report.Results {
-		for _, pkg := range result.Packages {
-			if len(pkg.Vulnerabilities) > 0 {
-				depNames[pkg.Package.Name] = VulnDep{
-					packageName: pkg.Package.Name,
-					osvVulnId:   pkg.Vulnerabilities[0].ID,
-					osvVulnDesc: pkg.Vulnerabilities[0].Summary,
-				}
+	var entrypoints []sniper.Entrypoint
+	for _, file := range c.files {
+		filePath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		entrypoints = append(entrypoints, sniper.Entrypoint{Name: file, FilePath: filePath})
+	}
+
+	for _, spec := range c.entrypoints {
+		entrypoint, err := sniper.ParseEntrypoint(spec, projectRoot, c.pythonEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		entrypoints = append(entrypoints, entrypoint)
+	}
+
+	if c.findEntrypoints {
+		found, err := sniper.FindEntrypoints(projectRoot, c.pythonEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		// A file that is given as an argument is already an entrypoint
+		for _, entrypoint := range found {
+			if !slices.ContainsFunc(entrypoints, func(other sniper.Entrypoint) bool {
+				return other.FilePath == entrypoint.FilePath && other.Function == entrypoint.Function
+			}) {
+				entrypoints = append(entrypoints, entrypoint)
 			}
 		}
 	}
 
-	return depNames
+	return entrypoints, nil
 }
 
-func (c *Cli) Run() error {
-	// step 1: Run OSV Scanner to find out vulnerable dependencies
+// parseFile parses a python file, or returns it from the module cache if it was parsed before.
+func (c *Cli) parseFile(filePath string) (sniper.ParsedFile, error) {
+	if file, cached := c.moduleCache[filePath]; cached {
+		return file, nil
+	}
+
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	py, err := sniper.ParsePythonWithEnv(filePath, fileContent, c.pythonEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	c.moduleCache[filePath] = py
+	return py, nil
+}
+
+// scanLockfile finds vulnerable dependencies in the lockfile, either by
+// running the OSV scanner or by matching against a local OSV database.
+func (c *Cli) scanLockfile() (models.VulnerabilityResults, error) {
+	if c.offlineDBPath != "" {
+		db, err := vulndb.Load(c.offlineDBPath)
+		if err != nil {
+			return models.VulnerabilityResults{}, err
+		}
+
+		yellow := color.New(color.FgYellow).SprintFunc()
+		for _, err := range db.Skipped {
+			fmt.Fprintf(os.Stderr, "%s: skipped advisory file: %s\n", yellow("WARNING"), err)
+		}
+
+		c.db = db
+		return db.Scan(c.lockFilePath)
+	}
+
 	scannerConfig := osv.ScannerActions{
 		LockfilePaths: []string{c.lockFilePath},
 	}
 
 	result, err := osv.DoScan(scannerConfig, nil)
 	if err != nil && !errors.Is(err, osv.VulnerabilitiesFoundErr) {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (c *Cli) Run() error {
+	// step 1: Run OSV Scanner to find out vulnerable dependencies
+	result, err := c.scanLockfile()
+	if err != nil {
 		return err
 	}
 
@@ -128,79 +286,138 @@ func (c *Cli) Run() error {
 	green := color.New(color.FgGreen).Add(color.Bold).SprintFunc()
 	grey := color.New(color.FgHiBlue).Add(color.Bold).SprintFunc()
 
-	visitCallGraphNode := func(cgNode *sniper.CgNode, path []*sniper.CgNode) {
+	// Findings are printed once the call graph has been walked from every
+	// entrypoint, so that each one lists all the entrypoints that reach it.
+	var findings []*Finding
+	visitCallGraphNode := func(entrypoint *sniper.Entrypoint, cgNode *sniper.CgNode, path []*sniper.CgNode) {
 		packageName := cgNode.File.PackageName()
 		if packageName == nil {
 			return
 		}
 
-		// TODO: Should we early exit
-		if _, exists := vulnPackages[*packageName]; exists {
-			if cgNode.FuncName != nil {
-				fmt.Printf("%s: Vulnerabily found in dependency %s\n", bgRed("ALERT"), yellow(*packageName))
-
-				fmt.Println("Stack trace:")
-				for i, node := range path {
-					if i == 0 {
-						continue
-					}
-
-					if node.FuncName != nil {
-						filePath, _ := filepath.Rel(*node.File.Module().ProjectRoot, node.File.Module().FileName)
-						prefix := "which calls "
-
-						if i == 1 {
-							prefix = "in function "
-						}
-
-						suffix := ""
-						packageName := node.File.PackageName()
-						if packageName != nil {
-							suffix = fmt.Sprintf(" (package %s) ", grey(*packageName))
-						}
-
-						fmt.Printf(
-							"    %s%s in %s%s\n", prefix,
-							yellow(*node.FuncName), filePath, suffix,
-						)
-					}
-				}
+		// Distribution names are compared in their PEP 503 normalized form,
+		// since the lockfile and site-packages can spell them differently.
+		depKey := vulndb.NormalizeName(string(models.EcosystemPyPI), *packageName)
 
-				fmt.Print("\nVulnerability details:\n")
-				fmt.Printf("%s: %s\n", green("ID"), vulnPackages[*packageName].osvVulnId)
-				fmt.Printf("%s: %s\n", green("Description"), vulnPackages[*packageName].osvVulnDesc)
+		// TODO: Should we early exit
+		vulnDep, exists := vulnPackages[depKey]
+		if !exists || cgNode.FuncName == nil {
+			return
+		}
 
-				fmt.Print("\n\n")
-				delete(vulnPackages, *packageName)
+		if !vulnDep.driftReported && vulnDep.hasDrifted(cgNode) {
+			installedVersion := vulnDep.versionOf(cgNode)
+			fmt.Fprintf(
+				os.Stderr, "%s: %s is pinned to version %s in the lockfile, but version %s is installed. ",
+				yellow("WARNING"), *packageName, vulnDep.version, installedVersion,
+			)
+
+			// The OSV scanner only matches the lockfile, so without an offline database, the
+			// advisories of the installed version that the lockfile version doesn't have are missed.
+			if c.db != nil {
+				vulnDep.addAdvisoriesOf(c.db, installedVersion)
+				fmt.Fprintln(
+					os.Stderr, "Findings for this package are based on the installed version, "+
+						"and its advisories were looked up in the offline database.",
+				)
+			} else {
+				fmt.Fprintln(
+					os.Stderr, "Findings for this package are based on the installed version, but advisories "+
+						"were only checked against the lockfile version (use --offline-db to check both).",
+				)
 			}
+			vulnDep.driftReported = true
 		}
+
+		findings = vulnDep.findingsOf(findings, entrypoint.Name, cgNode, path)
 	}
 
-	for _, file := range c.files {
-		file, err := filepath.Abs(file)
-		if err != nil {
-			return err
+	printFinding := func(finding *Finding) {
+		fmt.Printf("%s: Vulnerabily found in dependency %s\n", bgRed("ALERT"), yellow(finding.packageName))
+		fmt.Printf("Reachable from entrypoints %s\n", yellow(strings.Join(finding.entrypoints(), ", ")))
+
+		path := finding.path
+		fmt.Println("Stack trace:")
+		for i, node := range path {
+			if i == 0 {
+				continue
+			}
+
+			if node.FuncName != nil {
+				filePath, _ := filepath.Rel(*node.File.Module().ProjectRoot, node.File.Module().FileName)
+				prefix := "which calls "
+
+				if i == 1 {
+					prefix = "in function "
+				} else if path[i-1].EdgeKindOf(node) == sniper.EdgeCallback {
+					prefix = "which may call (as a callback) "
+				} else if path[i-1].EdgeKindOf(node) == sniper.EdgeImport {
+					prefix = "which imports "
+				} else if path[i-1].EdgeKindOf(node) == sniper.EdgeDynamic {
+					prefix = "which may call (looked up by name) "
+				}
+
+				suffix := ""
+				packageName := node.File.PackageName()
+				if packageName != nil {
+					suffix = fmt.Sprintf(" (package %s) ", grey(*packageName))
+				}
+
+				fmt.Printf(
+					"    %s%s in %s%s\n", prefix,
+					yellow(*node.FuncName), filePath, suffix,
+				)
+			}
 		}
 
-		fileContent, err := os.ReadFile(file)
-		if err != nil {
-			return err
+		fmt.Print("\nVulnerability details:\n")
+		for _, advisory := range finding.advisories {
+			fmt.Printf("%s: %s\n", green("ID"), strings.Join(advisory.ids, ", "))
+			if len(advisory.aliases) > 0 {
+				fmt.Printf("%s: %s\n", green("Aliases"), strings.Join(advisory.aliases, ", "))
+			}
+			fmt.Printf("%s: %s\n", green("Description"), advisory.summary)
 		}
 
-		py, err := sniper.ParsePython(file, fileContent)
+		fmt.Print("\n\n")
+	}
+
+	entrypoints, err := c.collectEntrypoints()
+	if err != nil {
+		return err
+	}
+
+	if len(entrypoints) == 0 {
+		fmt.Fprintf(
+			os.Stderr, "%s: no entrypoints to start from, pass files, --entrypoint or --find-entrypoints\n",
+			yellow("WARNING"),
+		)
+		return nil
+	}
+
+	callGraph := sniper.NewCallGraph()
+	callGraph.ModuleCache = c.moduleCache
+	callGraph.Options = sniper.CallGraphOptions{Callbacks: c.callbacks}
+
+	for _, entrypoint := range entrypoints {
+		file, err := c.parseFile(entrypoint.FilePath)
 		if err != nil {
 			return err
 		}
 
-		callGraph := sniper.CallGraphFromFile(py, c.moduleCache)
-
-		if c.showDotGraph {
-		 dotGraph := sniper.Cg2Dg(callGraph)
-		 fmt.Println(dotGraph.String())
-		} else {
-			callGraph.Walk(c.files[0], visitCallGraphNode)
+		if err := callGraph.AddEntrypoint(file, entrypoint); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", yellow("WARNING"), err)
 		}
+	}
 
+	if c.showDotGraph {
+		dotGraph := sniper.Cg2Dg(callGraph)
+		fmt.Println(dotGraph.String())
+	} else {
+		callGraph.Walk(visitCallGraphNode)
+		for _, finding := range findings {
+			printFinding(finding)
+		}
 	}
 
 	return nil
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/google/osv-scanner/pkg/grouper"
+	"github.com/google/osv-scanner/pkg/models"
+	"github.com/srijanpaul-deepsource/reachable/pkg/sniper"
+	"github.com/srijanpaul-deepsource/reachable/pkg/vulndb"
+)
+
+// Advisory is a single vulnerability in a dependency.
+// OSV advisories that are aliases of each other (e.g: a CVE, a GHSA and a PYSEC entry
+// that describe the same issue) are merged into one Advisory.
+type Advisory struct {
+	// ids are the OSV IDs of all merged advisories
+	ids []string
+	// aliases are the IDs (like CVEs) that the merged advisories are known by
+	aliases []string
+	summary string
+	// symbols are the vulnerable functions listed in the advisories.
+	// Empty if none of them have symbol-level information.
+	symbols []vulndb.Symbol
+	// entrypoints are the names of the entrypoints that the advisory
+	// has been found to be reachable from, in the order they were found
+	entrypoints []string
+	// vulns are the OSV entries that were merged into this advisory
+	vulns []models.Vulnerability
+	// installedOnly is set on the advisories that were found for the installed version
+	// of the package, and not for the version in the lockfile (see `addAdvisoriesOf`).
+	installedOnly bool
+}
+
+// affectsVersion returns `true` if any of the merged OSV entries
+// affect `version` of the package.
+func (advisory *Advisory) affectsVersion(ecosystem, packageName, version string) bool {
+	for _, vuln := range advisory.vulns {
+		if vulndb.IsAffected(vuln, ecosystem, packageName, version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isVulnerableFunc returns `true` if `cgNode` is one of the functions that the
+// advisory lists as vulnerable. When the advisory has no symbol information,
+// any function in the package is considered vulnerable.
+func (advisory *Advisory) isVulnerableFunc(cgNode *sniper.CgNode) bool {
+	if len(advisory.symbols) == 0 {
+		return true
+	}
+
+	moduleName := cgNode.File.ModuleName()
+	if moduleName == nil || cgNode.QualifiedName == nil {
+		return false
+	}
+
+	for _, symbol := range advisory.symbols {
+		if symbol.Matches(*moduleName, *cgNode.QualifiedName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VulnDep is a dependency with one or more known vulnerabilities.
+type VulnDep struct {
+	packageName string
+	ecosystem   string
+	// version is the version of the package in the lockfile
+	version    string
+	advisories []*Advisory
+	// driftReported is set once we've warned about the installed
+	// version of this package being different from the lockfile.
+	driftReported bool
+}
+
+// versionOf returns the version of the package that `cgNode` was analyzed from.
+// This is the version installed in site-packages if it is known,
+// or the version in the lockfile otherwise.
+func (dep *VulnDep) versionOf(cgNode *sniper.CgNode) string {
+	installedVersion := cgNode.File.PackageVersion()
+	if installedVersion == nil {
+		return dep.version
+	}
+
+	return *installedVersion
+}
+
+// hasDrifted returns `true` if the version of the package that `cgNode` was analyzed from
+// is different from the version in the lockfile.
+func (dep *VulnDep) hasDrifted(cgNode *sniper.CgNode) bool {
+	return !vulndb.EqualVersions(dep.ecosystem, dep.version, dep.versionOf(cgNode))
+}
+
+// addAdvisoriesOf adds the advisories in an OSV database that affect a version of the
+// package (like the installed version, when it differs from the lockfile), and that
+// the package doesn't have yet.
+func (dep *VulnDep) addAdvisoriesOf(db *vulndb.DB, version string) {
+	pkg := models.PackageVulns{
+		Package:         models.PackageInfo{Name: dep.packageName, Version: version, Ecosystem: dep.ecosystem},
+		Vulnerabilities: db.VulnerabilitiesOf(dep.ecosystem, dep.packageName, version),
+	}
+
+	for _, advisory := range advisoriesOf(pkg) {
+		isKnown := slices.ContainsFunc(dep.advisories, func(known *Advisory) bool {
+			return slices.ContainsFunc(advisory.ids, func(id string) bool { return slices.Contains(known.ids, id) })
+		})
+
+		if !isKnown {
+			advisory.installedOnly = true
+			dep.advisories = append(dep.advisories, advisory)
+		}
+	}
+}
+
+// reachedFrom records that the advisory is reachable from an entrypoint.
+func (advisory *Advisory) reachedFrom(entrypoint string) {
+	if !slices.Contains(advisory.entrypoints, entrypoint) {
+		advisory.entrypoints = append(advisory.entrypoints, entrypoint)
+	}
+}
+
+// unreachedAdvisoriesFor returns the advisories that are reached by calling `cgNode`,
+// and haven't been found to be reachable from `entrypoint` yet.
+// Advisories that don't affect the installed version of the package are skipped.
+func (dep *VulnDep) unreachedAdvisoriesFor(entrypoint string, cgNode *sniper.CgNode) []*Advisory {
+	version := dep.versionOf(cgNode)
+
+	var advisories []*Advisory
+	for _, advisory := range dep.advisories {
+		if slices.Contains(advisory.entrypoints, entrypoint) || !advisory.isVulnerableFunc(cgNode) {
+			continue
+		}
+
+		if (dep.hasDrifted(cgNode) || advisory.installedOnly) && !advisory.affectsVersion(dep.ecosystem, dep.packageName, version) {
+			continue
+		}
+
+		advisories = append(advisories, advisory)
+	}
+
+	return advisories
+}
+
+// Finding is a path from an entrypoint to a vulnerable function of a dependency,
+// along with the advisories that were first found to be reachable through it.
+type Finding struct {
+	packageName string
+	path        []*sniper.CgNode
+	advisories  []*Advisory
+}
+
+// entrypoints returns the names of every entrypoint that the
+// advisories of a finding are reachable from, without duplicates.
+func (finding *Finding) entrypoints() []string {
+	var entrypoints []string
+	for _, advisory := range finding.advisories {
+		for _, entrypoint := range advisory.entrypoints {
+			if !slices.Contains(entrypoints, entrypoint) {
+				entrypoints = append(entrypoints, entrypoint)
+			}
+		}
+	}
+
+	return entrypoints
+}
+
+// findingsOf adds the advisories of a dependency that an entrypoint reaches by calling `cgNode`
+// (through `path`) to `findings`. Advisories that were already reached from another entrypoint
+// are recorded on their existing finding, and the others start a new one.
+func (dep *VulnDep) findingsOf(findings []*Finding, entrypoint string, cgNode *sniper.CgNode, path []*sniper.CgNode) []*Finding {
+	var unreached []*Advisory
+	for _, advisory := range dep.unreachedAdvisoriesFor(entrypoint, cgNode) {
+		if len(advisory.entrypoints) == 0 {
+			unreached = append(unreached, advisory)
+		}
+		advisory.reachedFrom(entrypoint)
+	}
+
+	if len(unreached) == 0 {
+		return findings
+	}
+
+	packageName := dep.packageName
+	if installedName := cgNode.File.PackageName(); installedName != nil {
+		packageName = *installedName
+	}
+
+	return append(findings, &Finding{
+		packageName: packageName,
+		path:        slices.Clone(path),
+		advisories:  unreached,
+	})
+}
+
+// advisoriesOf merges the vulnerabilities of a package into advisories,
+// grouping together vulnerabilities that are aliases of each other.
+func advisoriesOf(pkg models.PackageVulns) []*Advisory {
+	groups := pkg.Groups
+	if len(groups) == 0 {
+		groups = grouper.Group(grouper.ConvertVulnerabilityToIDAliases(pkg.Vulnerabilities))
+	}
+
+	var advisories []*Advisory
+	for _, group := range groups {
+		advisory := &Advisory{ids: group.IDs}
+		for _, alias := range group.Aliases {
+			if !slices.Contains(group.IDs, alias) {
+				advisory.aliases = append(advisory.aliases, alias)
+			}
+		}
+
+		for _, vuln := range pkg.Vulnerabilities {
+			if !slices.Contains(group.IDs, vuln.ID) {
+				continue
+			}
+
+			advisory.vulns = append(advisory.vulns, vuln)
+			if advisory.summary == "" {
+				advisory.summary = vuln.Summary
+			}
+
+			symbols := vulndb.SymbolsOf(vuln, pkg.Package.Ecosystem, pkg.Package.Name)
+			advisory.symbols = append(advisory.symbols, symbols...)
+		}
+
+		advisories = append(advisories, advisory)
+	}
+
+	return advisories
+}
+
+// collectVulnerableDepNames maps the normalized name of every
+// vulnerable package (see `vulndb.NormalizeName`) to its advisories.
+func collectVulnerableDepNames(report models.VulnerabilityResults) map[string]*VulnDep {
+	depNames := make(map[string]*VulnDep)
+	for _, result := range report.Results {
+		for _, pkg := range result.Packages {
+			if len(pkg.Vulnerabilities) == 0 {
+				continue
+			}
+
+			depKey := vulndb.NormalizeName(pkg.Package.Ecosystem, pkg.Package.Name)
+			dep, exists := depNames[depKey]
+			if !exists {
+				dep = &VulnDep{
+					packageName: pkg.Package.Name,
+					ecosystem:   pkg.Package.Ecosystem,
+					version:     pkg.Package.Version,
+				}
+				depNames[depKey] = dep
+			}
+
+			dep.advisories = append(dep.advisories, advisoriesOf(pkg)...)
+		}
+	}
+
+	return depNames
+}
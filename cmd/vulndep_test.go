@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/osv-scanner/pkg/models"
+	"github.com/srijanpaul-deepsource/reachable/pkg/sniper"
+	"github.com/srijanpaul-deepsource/reachable/pkg/testutil"
+	"github.com/srijanpaul-deepsource/reachable/pkg/vulndb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseInstalledFile writes a file of an installed distribution of `requests` (with version
+// `installedVersion`) to a site-packages directory, and parses it.
+func parseInstalledFile(t *testing.T, relPath, installedVersion string) sniper.ParsedFile {
+	sitePackages := filepath.Join(t.TempDir(), "venv", "lib", "python3.12", "site-packages")
+	testutil.WriteFiles(t, sitePackages, map[string]string{
+		relPath: "def get(url):\n\tpass\n",
+		"requests-" + installedVersion + ".dist-info/METADATA": "Name: requests\nVersion: " + installedVersion + "\n",
+	})
+
+	filePath := filepath.Join(sitePackages, relPath)
+	contents, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	py, err := sniper.ParsePythonWithEnv(filePath, contents, sniper.PythonEnv{ExtraPaths: []string{sitePackages}})
+	require.NoError(t, err)
+	return py
+}
+
+// cgNodeIn returns a call graph node for the function with a qualified name in `file`.
+func cgNodeIn(file sniper.ParsedFile, qualifiedName string) *sniper.CgNode {
+	funcName := qualifiedName[strings.LastIndex(qualifiedName, ".")+1:]
+	return &sniper.CgNode{FuncName: &funcName, QualifiedName: &qualifiedName, File: file}
+}
+
+// requestsVulns returns the report of a lockfile that pins `requests` to `version`,
+// with the vulnerabilities in `vulns` (which affect the package `requests`).
+func requestsVulns(version string, vulns ...models.Vulnerability) models.VulnerabilityResults {
+	for i := range vulns {
+		for j := range vulns[i].Affected {
+			vulns[i].Affected[j].Package = models.Package{Ecosystem: "PyPI", Name: "requests"}
+		}
+	}
+
+	return models.VulnerabilityResults{
+		Results: []models.PackageSource{{
+			Packages: []models.PackageVulns{{
+				Package:         models.PackageInfo{Name: "Requests", Version: version, Ecosystem: "PyPI"},
+				Vulnerabilities: vulns,
+			}},
+		}},
+	}
+}
+
+// affectedRange returns an affected package entry for the versions in [introduced, fixed).
+func affectedRange(introduced, fixed string) []models.Affected {
+	return []models.Affected{{
+		Ranges: []models.Range{{
+			Type:   models.RangeEcosystem,
+			Events: []models.Event{{Introduced: introduced}, {Fixed: fixed}},
+		}},
+	}}
+}
+
+func Test_AdvisoriesGroupedByAlias(t *testing.T) {
+	type advisory struct {
+		ids     []string
+		aliases []string
+		summary string
+	}
+
+	cases := map[string]struct {
+		vulns []models.Vulnerability
+		want  []advisory
+	}{
+		"aliases of each other": {
+			vulns: []models.Vulnerability{
+				{ID: "PYSEC-2023-74", Aliases: []string{"CVE-2023-32681"}, Summary: "Proxy-Authorization leak"},
+				{ID: "GHSA-j8r2-6x86-q33q", Aliases: []string{"CVE-2023-32681"}},
+			},
+			want: []advisory{{
+				ids:     []string{"GHSA-j8r2-6x86-q33q", "PYSEC-2023-74"},
+				aliases: []string{"CVE-2023-32681"},
+				summary: "Proxy-Authorization leak",
+			}},
+		},
+		"unrelated": {
+			vulns: []models.Vulnerability{
+				{ID: "PYSEC-2018-28", Summary: "Credentials sent over HTTP"},
+				{ID: "PYSEC-2023-74", Summary: "Proxy-Authorization leak"},
+			},
+			want: []advisory{
+				{ids: []string{"PYSEC-2018-28"}, summary: "Credentials sent over HTTP"},
+				{ids: []string{"PYSEC-2023-74"}, summary: "Proxy-Authorization leak"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		deps := collectVulnerableDepNames(requestsVulns("2.30.0", tc.vulns...))
+		require.Contains(t, deps, "requests", name)
+
+		var got []advisory
+		for _, adv := range deps["requests"].advisories {
+			got = append(got, advisory{ids: adv.ids, aliases: adv.aliases, summary: adv.summary})
+		}
+		assert.Equal(t, tc.want, got, name)
+	}
+}
+
+func Test_FindingsReachedViaSeveralSymbols(t *testing.T) {
+	type reach struct {
+		entrypoint    string
+		qualifiedName string
+	}
+
+	cases := map[string]struct {
+		reaches []reach
+		// want lists the entrypoints of every finding
+		want [][]string
+	}{
+		"several symbols from one entrypoint": {
+			reaches: []reach{{"cli", "get"}, {"cli", "Session.request"}},
+			want:    [][]string{{"cli"}},
+		},
+		"several symbols from several entrypoints": {
+			reaches: []reach{{"cli", "get"}, {"worker", "Session.request"}, {"worker", "get"}},
+			want:    [][]string{{"cli", "worker"}},
+		},
+		"symbols that aren't vulnerable": {
+			reaches: []reach{{"cli", "head"}, {"worker", "Session.send"}},
+			want:    nil,
+		},
+	}
+
+	api := parseInstalledFile(t, "requests/api.py", "2.30.0")
+	vuln := models.Vulnerability{
+		ID:       "GHSA-j8r2-6x86-q33q",
+		Affected: affectedRange("2.3.0", "2.31.0"),
+	}
+	vuln.Affected[0].EcosystemSpecific = map[string]interface{}{
+		"imports": []interface{}{
+			map[string]interface{}{
+				"path":    "requests",
+				"symbols": []interface{}{"get", "Session.request"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		dep := collectVulnerableDepNames(requestsVulns("2.30.0", vuln))["requests"]
+		require.NotNil(t, dep, name)
+
+		var findings []*Finding
+		for _, reach := range tc.reaches {
+			cgNode := cgNodeIn(api, reach.qualifiedName)
+			findings = dep.findingsOf(findings, reach.entrypoint, cgNode, []*sniper.CgNode{cgNode})
+		}
+
+		var got [][]string
+		for _, finding := range findings {
+			assert.Equal(t, "requests", finding.packageName, name)
+			got = append(got, finding.entrypoints())
+		}
+		assert.Equal(t, tc.want, got, name)
+	}
+}
+
+func Test_FindingsWithInstalledVersionDrift(t *testing.T) {
+	cases := map[string]struct {
+		lockfileVersion string
+		installed       string
+		drifted         bool
+		want            []string
+	}{
+		"installed version matches the lockfile": {
+			lockfileVersion: "2.30.0",
+			installed:       "2.30",
+			drifted:         false,
+			want:            []string{"ADVISORY-OLD", "ADVISORY-NEW"},
+		},
+		"installed version is affected": {
+			lockfileVersion: "2.25.0",
+			installed:       "2.31.0",
+			drifted:         true,
+			want:            []string{"ADVISORY-NEW"},
+		},
+		"installed version is fixed": {
+			lockfileVersion: "2.30.0",
+			installed:       "2.32.0",
+			drifted:         true,
+			want:            nil,
+		},
+	}
+
+	for name, tc := range cases {
+		api := parseInstalledFile(t, "requests/api.py", tc.installed)
+		dep := collectVulnerableDepNames(requestsVulns(
+			tc.lockfileVersion,
+			models.Vulnerability{ID: "ADVISORY-OLD", Affected: affectedRange("0", "2.31.0")},
+			models.Vulnerability{ID: "ADVISORY-NEW", Affected: affectedRange("2.31.0", "2.32.0")},
+		))["requests"]
+		require.NotNil(t, dep, name)
+
+		cgNode := cgNodeIn(api, "get")
+		assert.Equal(t, tc.drifted, dep.hasDrifted(cgNode), name)
+		assert.Equal(t, tc.installed, dep.versionOf(cgNode), name)
+
+		var got []string
+		for _, advisory := range dep.unreachedAdvisoriesFor("cli", cgNode) {
+			got = append(got, advisory.ids...)
+		}
+		assert.Equal(t, tc.want, got, name)
+	}
+}
+
+func Test_AdvisoriesOfInstalledVersion(t *testing.T) {
+	oldVuln := models.Vulnerability{ID: "ADVISORY-OLD", Affected: affectedRange("0", "2.31.0")}
+	newVuln := models.Vulnerability{ID: "ADVISORY-NEW", Affected: affectedRange("2.31.0", "2.32.0")}
+
+	dbDir := t.TempDir()
+	for _, vuln := range []models.Vulnerability{oldVuln, newVuln} {
+		vuln.Affected[0].Package = models.Package{Ecosystem: "PyPI", Name: "requests"}
+		contents, err := json.Marshal(vuln)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dbDir, vuln.ID+".json"), contents, 0o644))
+	}
+
+	db, err := vulndb.Load(dbDir)
+	require.NoError(t, err)
+
+	// Only the advisories of the lockfile version are known until the DB is queried for the installed version
+	dep := collectVulnerableDepNames(requestsVulns("2.25.0", oldVuln))["requests"]
+	require.NotNil(t, dep)
+
+	installed := cgNodeIn(parseInstalledFile(t, "requests/api.py", "2.31.0"), "get")
+	assert.Empty(t, dep.unreachedAdvisoriesFor("cli", installed))
+
+	// Advisories that the package has already aren't added again
+	dep.addAdvisoriesOf(db, dep.versionOf(installed))
+	dep.addAdvisoriesOf(db, "2.25.0")
+	assert.Len(t, dep.advisories, 2)
+
+	var got []string
+	for _, advisory := range dep.unreachedAdvisoriesFor("cli", installed) {
+		got = append(got, advisory.ids...)
+	}
+	assert.Equal(t, []string{"ADVISORY-NEW"}, got)
+
+	// The advisories of the installed version don't apply to the lockfile version
+	pinned := cgNodeIn(parseInstalledFile(t, "requests/api.py", "2.25.0"), "get")
+	got = nil
+	for _, advisory := range dep.unreachedAdvisoriesFor("cli", pinned) {
+		got = append(got, advisory.ids...)
+	}
+	assert.Equal(t, []string{"ADVISORY-OLD"}, got)
+}
@@ -3,7 +3,11 @@ module github.com/srijanpaul-deepsource/reachable
 go 1.22.4
 
 require (
+	deps.dev/util/semver v0.0.0-20240701054435-542fb1833d6b
+	github.com/BurntSushi/toml v1.4.0
 	github.com/emicklei/dot v1.6.2
+	github.com/fatih/color v1.17.0
+	github.com/google/osv-scanner v1.8.2
 	github.com/smacker/go-tree-sitter v0.0.0-20240625050157-a31a98a7c0f6
 	github.com/stretchr/testify v1.9.0
 )
@@ -13,8 +17,6 @@ require (
 	deps.dev/api/v3 v3.0.0-20240701054435-542fb1833d6b // indirect
 	deps.dev/util/maven v0.0.0-20240701054435-542fb1833d6b // indirect
 	deps.dev/util/resolve v0.0.0-20240701054435-542fb1833d6b // indirect
-	deps.dev/util/semver v0.0.0-20240701054435-542fb1833d6b // indirect
-	github.com/BurntSushi/toml v1.4.0 // indirect
 	github.com/CycloneDX/cyclonedx-go v0.9.0 // indirect
 	github.com/Microsoft/go-winio v0.6.1 // indirect
 	github.com/ProtonMail/go-crypto v1.0.0 // indirect
@@ -25,13 +27,11 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dghubble/trie v0.1.0 // indirect
 	github.com/emirpasic/gods v1.18.1 // indirect
-	github.com/fatih/color v1.17.0 // indirect
 	github.com/go-git/gcfg v1.5.1-0.20230307220236-3a3c6141e376 // indirect
 	github.com/go-git/go-billy/v5 v5.5.0 // indirect
 	github.com/go-git/go-git/v5 v5.12.0 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/go-containerregistry v0.19.2 // indirect
-	github.com/google/osv-scanner v1.8.2 // indirect
 	github.com/ianlancetaylor/demangle v0.0.0-20240312041847-bd984b5ce465 // indirect
 	github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
 	github.com/jedib0t/go-pretty/v6 v6.5.9 // indirect
@@ -0,0 +1,73 @@
+package sniper
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// classesOfAnnotation returns the definitions of the classes that a type annotation names
+// (see `ParsedFile.AnnotatedTypesOf`). Names are looked up in the scope of the annotation,
+// and types that can't be resolved to a class (like `int`) are left out.
+func (cg *CallGraph) classesOfAnnotation(file ParsedFile, annotation *sitter.Node, typeNames []string) []classRef {
+	// An annotation may refer to itself through a name bound to it (e.g: `x: x.T`)
+	if depth, inProgress := cg.instanceLookups[annotation]; inProgress {
+		cg.flow.leaveOut(depth)
+		return nil
+	}
+
+	cg.instanceLookups[annotation] = cg.flow.depth()
+	defer delete(cg.instanceLookups, annotation)
+
+	var classes []classRef
+	for _, typeName := range typeNames {
+		for _, def := range cg.resolveDottedName(file, annotation, typeName) {
+			if def.file.IsClassDef(def.node) {
+				// Annotations in stubs refer to the classes declared by stubs
+				for _, impl := range cg.implementationsOf(def.file, def.node) {
+					classes = append(classes, classRef{File: impl.file, Class: impl.node})
+				}
+			}
+		}
+	}
+
+	return classes
+}
+
+// resolveDottedName resolves a dotted name (like `httpx.Client`) that is mentioned by the node `at`
+// to its definitions, the same way as an attribute expression that spells it out would be resolved.
+func (cg *CallGraph) resolveDottedName(file ParsedFile, at *sitter.Node, dottedName string) []flowValue {
+	parts := strings.Split(dottedName, ".")
+
+	var defs []flowValue
+	for _, decl := range cg.resolveName(file, at, parts[0]) {
+		defs = append(defs, cg.resolveExprs(decl.file, decl.node)...)
+	}
+
+	for _, part := range parts[1:] {
+		var attributes []flowValue
+		for _, def := range defs {
+			for _, attribute := range cg.resolveAttribute(def.file, def.node, part) {
+				attributes = append(attributes, cg.resolveExprs(attribute.file, attribute.node)...)
+			}
+		}
+		defs = attributes
+	}
+
+	return defs
+}
+
+// annotatedInstance returns the instance that a type annotation stands for, which is the
+// annotation itself (see `resolveClassesOfInstance`). Returns nil if the annotation is nil,
+// or if it doesn't name a known class.
+func (cg *CallGraph) annotatedInstance(file ParsedFile, annotation *sitter.Node) []flowValue {
+	if annotation == nil {
+		return nil
+	}
+
+	if len(cg.resolveClassesOfInstance(file, annotation)) == 0 {
+		return nil
+	}
+
+	return []flowValue{{file: file, node: annotation}}
+}
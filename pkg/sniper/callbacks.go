@@ -0,0 +1,58 @@
+package sniper
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// findCallbacks returns the call graph nodes of functions that `callExpr` may call indirectly:
+//  1. Functions passed as arguments, like `worker` in `Thread(target=worker)`
+//     or the functions in `register_all([on_start, on_stop])`.
+//  2. Functions in a container that is iterated over, when the loop variable is
+//     called (e.g: `h()` in `for h in [on_start, on_stop]: h()`).
+//
+// The callee is often a builtin (like `map`) or a library function that is shared
+// by all its callers, so these edges are added to the calling function instead.
+func (cg *CallGraph) findCallbacks(file ParsedFile, callExpr *sitter.Node) []*CgNode {
+	var callbacks []*CgNode
+	for _, arg := range file.ArgumentsOf(callExpr) {
+		callbacks = append(callbacks, cg.functionsReferencedBy(file, arg.Value, true)...)
+	}
+
+	callee := file.GetCallee(callExpr)
+	if callee == nil {
+		return callbacks
+	}
+
+	if iterable := file.LoopIterableOf(callee); iterable != nil {
+		for _, container := range cg.resolveExprs(file, iterable) {
+			for _, elem := range container.file.ElementsOf(container.node) {
+				callbacks = append(callbacks, cg.functionsReferencedBy(container.file, elem, false)...)
+			}
+		}
+	}
+
+	return callbacks
+}
+
+// functionsReferencedBy returns the call graph nodes of the functions that an
+// expression refers to. When `searchContainers` is true, the elements of a container
+// (like a list of functions) are searched too, but not the elements of nested containers.
+func (cg *CallGraph) functionsReferencedBy(file ParsedFile, expr *sitter.Node, searchContainers bool) []*CgNode {
+	var functions []*CgNode
+	for _, def := range cg.resolveExprs(file, expr) {
+		if def.file.IsFunctionDef(def.node) {
+			functions = append(functions, cg.traverseFunction(def.file, def.node))
+			continue
+		}
+
+		if !searchContainers {
+			continue
+		}
+
+		for _, elem := range def.file.ElementsOf(def.node) {
+			functions = append(functions, cg.functionsReferencedBy(def.file, elem, false)...)
+		}
+	}
+
+	return functions
+}
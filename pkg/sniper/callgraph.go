@@ -19,19 +19,89 @@ type CgNode struct {
 	Func *sitter.Node
 	// Name of the function being called
 	FuncName *string
+	// QualifiedName is the name of the function qualified by its
+	// enclosing classes and functions (e.g: `Session.request`).
+	// This is nil for unresolved functions.
+	QualifiedName *string
 	// Neighbors is a list of CgNodes for other functions that are called
 	// inside the body of `Func`
 	Neighbors []*CgNode
+	// NeighborKinds maps neighbors that aren't called directly to the kind of their edge.
+	// Neighbors that are missing from this map are called directly.
+	NeighborKinds map[*CgNode]EdgeKind
 	// The file that this call-graph node belongs to
 	File ParsedFile
 }
 
+// EdgeKind describes how a function reaches a neighbor in the call graph.
+// Kinds are ordered from the most certain to the least certain.
+type EdgeKind int
+
+const (
+	// EdgeCall is a direct call, like `f()`
+	EdgeCall EdgeKind = iota
+	// EdgeImport is an import statement, which runs the top-level
+	// code of the imported module (see `ModuleInitializerName`).
+	EdgeImport
+	// EdgeDynamic is a call or an import whose target is looked up by name at runtime, like
+	// `importlib.import_module("a")` or `getattr(a, "f")()`. The target is only known when
+	// its name is a constant string, so it may differ at runtime.
+	EdgeDynamic
+	// EdgeCallback is a function that is passed to another function as an argument
+	// (like `Thread(target=f)`), or iterated over and called. The callee may call it,
+	// so this edge is less certain than a direct call.
+	EdgeCallback
+)
+
+func (kind EdgeKind) String() string {
+	switch kind {
+	case EdgeImport:
+		return "import"
+	case EdgeDynamic:
+		return "dynamic"
+	case EdgeCallback:
+		return "callback"
+	default:
+		return "call"
+	}
+}
+
+// EdgeKindOf returns the kind of the edge from `cgNode` to `neighbor`.
+func (cgNode *CgNode) EdgeKindOf(neighbor *CgNode) EdgeKind {
+	return cgNode.NeighborKinds[neighbor]
+}
+
+// addNeighbor adds an edge of kind `kind` from `cgNode` to `neighbor`. When a neighbor
+// is reached by more than one kind of edge, the most certain kind is kept.
+func (cgNode *CgNode) addNeighbor(neighbor *CgNode, kind EdgeKind) {
+	previousKind, isNeighbor := cgNode.NeighborKinds[neighbor]
+	if !isNeighbor && slices.Contains(cgNode.Neighbors, neighbor) {
+		previousKind, isNeighbor = EdgeCall, true
+	}
+
+	cgNode.Neighbors = append(cgNode.Neighbors, neighbor)
+	if isNeighbor && previousKind <= kind {
+		return
+	}
+
+	if kind == EdgeCall {
+		delete(cgNode.NeighborKinds, neighbor)
+		return
+	}
+
+	if cgNode.NeighborKinds == nil {
+		cgNode.NeighborKinds = make(map[*CgNode]EdgeKind)
+	}
+	cgNode.NeighborKinds[neighbor] = kind
+}
+
 func NewCgNode(file ParsedFile, fn *sitter.Node) CgNode {
-	var funcName *string
+	var funcName, qualifiedName *string
 	if fn != nil {
 		funcName = file.NameOfFunction(fn)
+		qualifiedName = file.QualifiedNameOf(fn)
 	}
-	return CgNode{Func: fn, FuncName: funcName, File: file}
+	return CgNode{Func: fn, FuncName: funcName, QualifiedName: qualifiedName, File: file}
 }
 
 // CallGraph maps a function definition or call-expression AST node
@@ -43,6 +113,47 @@ type CallGraph struct {
 	// TODO: what about methods? `os.exec()`?
 	UnresolvedCgNodes map[string]*CgNode
 	ModuleCache       map[string]ParsedFile
+	Options           CallGraphOptions
+	// wildcardLookups are the names being looked up through wildcard imports
+	// right now, and is used to break cycles between modules that re-export each other.
+	// Like the other lookups in progress, it maps them to the depth of the value flow
+	// resolutions that they're nested in (see `valueFlow.leaveOut`).
+	wildcardLookups map[wildcardLookup]int
+	// importLookups are the imported names that are being resolved right now.
+	importLookups map[ImportedName]int
+	// calleesOfCall caches the call-graphs of the functions that a call-expression may call.
+	calleesOfCall map[*sitter.Node][]callTarget
+	// instanceLookups are the annotations whose classes are being resolved right now.
+	instanceLookups map[*sitter.Node]int
+	// mroCache maps a class definition to its method resolution order
+	mroCache map[*sitter.Node][]classRef
+	// dynamicLookups caches the values of calls that look a module or an attribute up by name
+	dynamicLookups map[*sitter.Node][]flowValue
+	// entrypoints are the entrypoints that `Walk` starts from (see `AddEntrypoint`)
+	entrypoints []entrypointRoots
+	// traversals is the number of functions being traversed right now
+	traversals int
+	// initializers are the module initializers that were reached while other functions were
+	// being traversed, and that are traversed once those are done (see `ModuleInitializer`)
+	initializers []*CgNode
+	// stubs maps the file name of a module to its parsed stub file (nil if it has none)
+	stubs map[string]ParsedFile
+	// flow finds the values passed to function parameters
+	flow *valueFlow
+}
+
+// wildcardLookup is a name being looked up through the wildcard imports of a file.
+type wildcardLookup struct {
+	filePath string
+	name     string
+}
+
+// CallGraphOptions configures which edges are added to a call graph
+// besides direct calls. All options are disabled by default.
+type CallGraphOptions struct {
+	// Callbacks adds edges to functions that are passed as arguments to a call,
+	// or iterated over in a container and called (see `EdgeCallback`).
+	Callbacks bool
 }
 
 // NewCallGraph creates an empty call graph
@@ -50,56 +161,104 @@ func NewCallGraph() *CallGraph {
 	return &CallGraph{
 		CallGraphOfNode:   make(map[*sitter.Node]*CgNode),
 		UnresolvedCgNodes: make(map[string]*CgNode),
+		ModuleCache:       make(map[string]ParsedFile),
+		wildcardLookups:   make(map[wildcardLookup]int),
+		importLookups:     make(map[ImportedName]int),
+		calleesOfCall:     make(map[*sitter.Node][]callTarget),
+		instanceLookups:   make(map[*sitter.Node]int),
+		mroCache:          make(map[*sitter.Node][]classRef),
+		dynamicLookups:    make(map[*sitter.Node][]flowValue),
+		stubs:             make(map[string]ParsedFile),
+		flow:              newValueFlow(),
 	}
 }
 
 // FindCallGraph finds a call-graph corresponding to a call-expression node.
+// Decorators that call their expression (see `ParsedFile.IsDecoratorCall`) are treated as call expressions.
+// When the call may call more than one function, the call-graph of the first one is returned.
 func (cg *CallGraph) FindCallGraph(file ParsedFile, node *sitter.Node) *CgNode {
-	if !file.IsCallExpr(node) {
+	targets := cg.findCallGraphs(file, node)
+	if len(targets) == 0 {
+		return nil
+	}
+
+	return targets[0].cgNode
+}
+
+// callTarget is the call-graph of a function that is reached from another
+// function, along with the kind of the edge that reaches it.
+type callTarget struct {
+	cgNode *CgNode
+	kind   EdgeKind
+}
+
+// findCallGraphs finds the call-graphs of every function that a call-expression may call.
+// A callee can have many definitions (e.g: `json` in `try: import ujson as json ...`), and
+// each one of them gets a call-graph. Callees that are looked up by name at runtime
+// (e.g: with `getattr` in python) are reached by an `EdgeDynamic`.
+func (cg *CallGraph) findCallGraphs(file ParsedFile, node *sitter.Node) []callTarget {
+	if !file.IsCallExpr(node) && !file.IsDecoratorCall(node) {
 		// We do not resolve
 		return nil
 	}
 
+	cg.indexCallSites(file, file.Module().Ast)
+
 	// Check if a cached call-graph exists
-	cached, exists := cg.CallGraphOfNode[node]
+	cached, exists := cg.calleesOfCall[node]
 	if exists {
 		return cached
 	}
 
-	// If not, find the function that the call-expression is calling.
-	// TODO(@Tushar/Srijan): Make this work for methods and not just identifiers
-	nextFile, nextNode := cg.resolveCallExpr(file, node)
-	if nextNode == nil {
-		calleeName := file.GetCalleeName(node)
-		if calleeName != nil {
-			cgNode, exists := cg.UnresolvedCgNodes[*calleeName]
-			if exists {
-				return cgNode
-			}
+	// If not, find the functions that the call-expression is calling.
+	var targets []callTarget
+	for _, callee := range cg.resolveCallees(file, node) {
+		kind := EdgeCall
+		if callee.dynamic {
+			kind = EdgeDynamic
 		}
 
-		cgNode := &CgNode{FuncName: calleeName, File: file}
-		if calleeName != nil {
-			cg.UnresolvedCgNodes[*calleeName] = cgNode
+		if callee.file.IsImport(callee.node) {
+			calleeName := callee.file.GetCalleeName(node)
+			if calleeName != nil {
+				for _, cgNode := range cg.cgNodesFromImport(callee.file, callee.node, *calleeName) {
+					targets = append(targets, callTarget{cgNode: cgNode, kind: kind})
+				}
+			}
+			continue
 		}
-		return cgNode
+
+		// Traverse the body of that function, and create the call-graph.
+		targets = append(targets, callTarget{cgNode: cg.traverseFunction(callee.file, callee.node), kind: kind})
 	}
 
-	if nextFile.IsImport(nextNode) {
-		calleeName := nextFile.GetCalleeName(node)
-		if calleeName == nil {
-			return nil
-		}
+	if len(targets) == 0 {
+		targets = []callTarget{{cgNode: cg.unresolvedCgNode(file, node), kind: EdgeCall}}
+	}
 
-		cgNode := cg.cgNodeFromImport(nextFile, nextNode, *calleeName)
-		if cgNode != nil {
-			cg.CallGraphOfNode[node] = cgNode
-		}
+	cg.calleesOfCall[node] = targets
+	return targets
+}
+
+// unresolvedCgNode returns the stub call-graph node for a call whose callee can't be resolved.
+func (cg *CallGraph) unresolvedCgNode(file ParsedFile, callExpr *sitter.Node) *CgNode {
+	// A call that is resolved again (see `refreshStaleFunctions`) keeps its stub
+	if cgNode, exists := cg.CallGraphOfNode[callExpr]; exists && cgNode.Func == nil {
 		return cgNode
 	}
 
-	// Traverse the body of that function, and create the call-graph.
-	cgNode := cg.traverseFunction(nextFile, nextNode)
+	calleeName := file.GetCalleeName(callExpr)
+	if calleeName != nil {
+		cgNode, exists := cg.UnresolvedCgNodes[*calleeName]
+		if exists {
+			return cgNode
+		}
+	}
+
+	cgNode := &CgNode{FuncName: calleeName, File: file}
+	if calleeName != nil {
+		cg.UnresolvedCgNodes[*calleeName] = cgNode
+	}
 	return cgNode
 }
 
@@ -110,6 +269,18 @@ type callExprWalker struct {
 	file          ParsedFile
 	currentCgNode *CgNode
 	cg            *CallGraph
+	// refresh is `true` when a function that was traversed before is traversed again
+	// (see `refreshStaleFunctions`), so only the edges that it didn't have yet are added.
+	refresh bool
+}
+
+// addNeighbor adds an edge from the function being traversed.
+func (walker *callExprWalker) addNeighbor(neighbor *CgNode, kind EdgeKind) {
+	if walker.refresh && slices.Contains(walker.currentCgNode.Neighbors, neighbor) {
+		return
+	}
+
+	walker.currentCgNode.addNeighbor(neighbor, kind)
 }
 
 // OnEnterNode is needed by `Walker` interface
@@ -130,14 +301,57 @@ func (walker *callExprWalker) OnEnterNode(node *sitter.Node) bool {
 		return false
 	}
 
-	if walker.file.IsCallExpr(node) {
-		cgNode := walker.cg.FindCallGraph(walker.file, node)
-		if cgNode == nil {
+	// Importing a module runs its top-level code (and the top-level code of its packages).
+	if walker.file.IsImport(node) {
+		for _, filePath := range walker.file.ModulesRunBy(node) {
+			importedFile := walker.cg.parseImportedFile(walker.file, filePath)
+			if importedFile != nil {
+				walker.addNeighbor(walker.cg.ModuleInitializer(importedFile), EdgeImport)
+			}
+		}
+		return false
+	}
+
+	if walker.cg.Options.Callbacks && walker.file.IsCallExpr(node) {
+		for _, callback := range walker.cg.findCallbacks(walker.file, node) {
+			walker.addNeighbor(callback, EdgeCallback)
+		}
+	}
+
+	// Decorators run when the decorated definition is evaluated, so they are
+	// called from the enclosing function, and not from the decorated one.
+	if walker.file.IsCallExpr(node) || walker.file.IsDecoratorCall(node) {
+		if walker.refresh {
+			// The callees may have changed since they were cached
+			delete(walker.cg.calleesOfCall, node)
+		}
+
+		targets := walker.cg.findCallGraphs(walker.file, node)
+		if len(targets) == 0 {
 			return true
 		}
 
-		walker.currentCgNode.Neighbors = append(walker.currentCgNode.Neighbors, cgNode)
-		walker.cg.CallGraphOfNode[node] = cgNode
+		for _, target := range targets {
+			walker.addNeighbor(target.cgNode, target.kind)
+		}
+		walker.cg.CallGraphOfNode[node] = targets[0].cgNode
+	}
+
+	// Importing a module by name (e.g: with `importlib.import_module` in python) runs its top-level code
+	// too, and so does the function that looks up a callee that a module doesn't define.
+	if walker.file.IsCallExpr(node) {
+		for _, module := range walker.cg.modulesRunByDynamicImport(walker.file, node) {
+			walker.addNeighbor(walker.cg.ModuleInitializer(module), EdgeDynamic)
+		}
+
+		for _, getattr := range walker.cg.findModuleGetattrCalls(walker.file, node) {
+			walker.addNeighbor(getattr, EdgeDynamic)
+		}
+	}
+
+	// Protocol methods (like `__enter__` in python) are called by the syntax that uses them.
+	for _, method := range walker.cg.findImplicitCalls(walker.file, node) {
+		walker.addNeighbor(method.cgNode, method.kind)
 	}
 
 	return true
@@ -158,6 +372,8 @@ func (cg *CallGraph) traverseFunction(file ParsedFile, fn *sitter.Node) *CgNode
 
 	cgNode := NewCgNode(file, fn)
 	cg.CallGraphOfNode[fn] = &cgNode
+	cg.flow.traversed = append(cg.flow.traversed, flowValue{file: file, node: fn})
+	cg.indexCallSites(file, fn)
 
 	walker := callExprWalker{
 		file:          file,
@@ -165,12 +381,125 @@ func (cg *CallGraph) traverseFunction(file ParsedFile, fn *sitter.Node) *CgNode
 		cg:            cg,
 	}
 
-	body := file.BodyOfFunction(fn)
-	util.WalkTree(body, &walker)
+	cg.traversals++
+	util.WalkTree(file.BodyOfFunction(fn), &walker)
+	cg.traversals--
 
+	cg.finishTraversals()
 	return &cgNode
 }
 
+// ModuleInitializerName is the name of the call graph node for the top-level code of a module.
+const ModuleInitializerName = "<module>"
+
+// ModuleInitializer returns the call graph node for the top-level code of a module
+// (named `ModuleInitializerName`), which runs when the module is imported.
+// Its neighbors are the functions called, and the modules imported, at the top level.
+// When it is reached while another function is being traversed, its top-level code is
+// only traversed once the outermost traversal is done, so that importing a module
+// doesn't traverse everything that it imports in the middle of an import statement.
+func (cg *CallGraph) ModuleInitializer(file ParsedFile) *CgNode {
+	ast := file.Module().Ast
+	cachedNode, cached := cg.CallGraphOfNode[ast]
+	if cached {
+		return cachedNode
+	}
+
+	name := ModuleInitializerName
+	cgNode := &CgNode{Func: ast, FuncName: &name, File: file}
+	cg.CallGraphOfNode[ast] = cgNode
+	cg.initializers = append(cg.initializers, cgNode)
+	cg.finishTraversals()
+	return cgNode
+}
+
+// finishTraversals traverses the module initializers that were reached (see `ModuleInitializer`)
+// and refreshes the stale functions (see `refreshStaleFunctions`) once the outermost traversal is done.
+func (cg *CallGraph) finishTraversals() {
+	if cg.traversals > 0 {
+		return
+	}
+
+	flow := cg.flow
+	cg.refreshStaleFunctions()
+	for {
+		for len(cg.initializers) > 0 {
+			cgNode := cg.initializers[0]
+			cg.initializers = cg.initializers[1:]
+			cg.traverseInitializer(cgNode)
+			cg.refreshStaleFunctions()
+		}
+
+		if !flow.changed || flow.refreshing {
+			return
+		}
+
+		// The values of parameters also flow into attributes and return values, which the
+		// calls of any traversed function may be resolved to (not only the calls in the
+		// functions whose parameters got the values), so every function is refreshed.
+		flow.changed = false
+		for _, fn := range flow.traversed {
+			flow.markStale(fn)
+		}
+		cg.refreshStaleFunctions()
+	}
+}
+
+// traverseInitializer traverses the top-level code of a module, and builds its call graph node.
+func (cg *CallGraph) traverseInitializer(cgNode *CgNode) {
+	file := cgNode.File
+	cg.indexCallSites(file, cgNode.Func)
+	walker := callExprWalker{
+		file:          file,
+		currentCgNode: cgNode,
+		cg:            cg,
+	}
+
+	cg.traversals++
+	util.WalkTree(cgNode.Func, &walker)
+	cg.traversals--
+}
+
+// refreshStaleFunctions traverses the functions whose parameters got new values after they
+// were traversed (see `addParamValues`) again, and adds edges to the functions that their
+// calls may call now. This is only done once the outermost traversal is done, so the calls
+// aren't resolved while the values of the parameters are still being propagated.
+func (cg *CallGraph) refreshStaleFunctions() {
+	flow := cg.flow
+	if cg.traversals > 0 || flow.refreshing {
+		return
+	}
+
+	flow.refreshing = true
+	defer func() { flow.refreshing = false }()
+
+	// The call sites of the traversed functions may pass new values
+	cg.solveValueFlow()
+	for len(flow.stale) > 0 {
+		stale := flow.stale[0]
+		flow.stale = flow.stale[1:]
+		delete(flow.isStale, stale.node)
+
+		// Nested functions can refer to the parameters of the functions that they're in
+		var refresh func(node *sitter.Node)
+		refresh = func(node *sitter.Node) {
+			if cgNode, traversed := cg.CallGraphOfNode[node]; traversed && stale.file.IsFunctionDef(node) {
+				walker := callExprWalker{file: stale.file, currentCgNode: cgNode, cg: cg, refresh: true}
+				cg.traversals++
+				util.WalkTree(stale.file.BodyOfFunction(node), &walker)
+				cg.traversals--
+			}
+
+			for i := 0; i < int(node.NamedChildCount()); i++ {
+				refresh(node.NamedChild(i))
+			}
+		}
+
+		refresh(stale.node)
+		cg.solveValueFlow()
+	}
+}
+
 // resolveExpr resolves an arbitrary expression to its initialization expr (a function/class definition)
 // e.g, In this snippet:
 // ```py
@@ -178,108 +507,309 @@ func (cg *CallGraph) traverseFunction(file ParsedFile, fn *sitter.Node) *CgNode
 // bar = foo
 // ```
 // The identifier "bar" will be resolved to the function definition `def foo(): ...`
+// When the expression may refer to more than one definition, the first one is returned (see `resolveExprs`).
 func (cg *CallGraph) resolveExpr(file ParsedFile, node *sitter.Node) (ParsedFile, *sitter.Node) {
-	for !file.IsFunctionDef(node) {
-		var nextFile ParsedFile
-		var nextNode *sitter.Node
-
-		if file.IsDottedExpr(node) {
-			nextFile, nextNode = cg.resolveDottedExpr(file, node)
-		} else if node.Type() == "identifier" {
-			nextNode = cg.resolveIdentifier(file, node)
-			if nextNode != nil && file.IsImport(nextNode) {
-				name := node.Content(file.Module().Source)
-				nextFile, nextNode = cg.resolveImport(file, nextNode, name)
-			}
-		} else {
-			break
+	defs := cg.resolveExprs(file, node)
+	if len(defs) == 0 {
+		return file, node
+	}
+
+	return defs[0].file, defs[0].node
+}
+
+// resolveExprs resolves an expression to every definition that it may refer to, in source order.
+// A name can be bound more than once, and any one of these bindings may reach the expression.
+// e.g: After `try: import ujson as json` and `except ImportError: import json`, the identifier
+// "json" is resolved to both the `ujson` and the `json` modules.
+// An expression that can't be resolved any further is its own definition.
+func (cg *CallGraph) resolveExprs(file ParsedFile, node *sitter.Node) []flowValue {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	var defs []flowValue
+	state := make(map[*sitter.Node]int)
+
+	// A definition that is reached through a lookup by name at runtime is dynamic.
+	var visit func(file ParsedFile, node *sitter.Node, dynamic bool)
+	visit = func(file ParsedFile, node *sitter.Node, dynamic bool) {
+		state[node] = visiting
+		defer func() { state[node] = visited }()
+
+		if file.IsFunctionDef(node) {
+			defs = append(defs, flowValue{file: file, node: node, dynamic: dynamic})
+			return
 		}
 
-		if nextFile != nil {
-			file = nextFile
+		// A node whose definitions all lead back to it (e.g: `a = b` and `b = a`) is its own definition.
+		resolved := false
+		for _, next := range cg.resolveStep(file, node) {
+			// Parameters (like `self`) are declarations that resolve to themselves.
+			if next.node == nil || next.node == node || state[next.node] == visiting {
+				continue
+			}
+
+			resolved = true
+			if state[next.node] != visited {
+				visit(next.file, next.node, dynamic || next.dynamic)
+			}
 		}
 
-		if nextNode != nil {
-			node = nextNode
-		} else {
-			break
+		if !resolved {
+			defs = append(defs, flowValue{file: file, node: node, dynamic: dynamic})
 		}
 	}
 
-	return file, node
+	visit(file, node, false)
+	return defs
 }
 
-func (cg *CallGraph) resolveIdentifier(file ParsedFile, idNode *sitter.Node) *sitter.Node {
-	module := file.Module()
+// resolveStep resolves an expression to the nodes that it is directly defined as.
+// Returns nil when the expression can't be resolved any further.
+func (cg *CallGraph) resolveStep(file ParsedFile, node *sitter.Node) []flowValue {
+	if file.IsDottedExpr(node) {
+		return cg.resolveDottedExpr(file, node)
+	}
+
+	if file.IsCallExpr(node) {
+		if values := cg.resolveDynamicLookup(file, node); len(values) > 0 {
+			return values
+		}
+		return cg.resolveReturnValues(file, node)
+	}
 
-	// 1. Find where this identifier was declared.
-	scope := GetScope(module, idNode)
+	if node.Type() != "identifier" {
+		return nil
+	}
+
+	return cg.resolveName(file, node, node.Content(file.Module().Source))
+}
+
+// resolveName resolves a name to the nodes that it is bound to, in the scope of `at`
+// (the identifier that refers to the name, or any other node that mentions it).
+func (cg *CallGraph) resolveName(file ParsedFile, at *sitter.Node, name string) []flowValue {
+	scope := GetScope(file.Module(), at)
 	if scope == nil {
 		return nil
 	}
 
-	// TODO(@srijan/tushar): check for infinite loops
-	initExpr := scope.Lookup(idNode.Content(module.Source))
-	return initExpr
+	decls := scope.LookupAll(name)
+	if len(decls) == 0 {
+		// Names that aren't declared anywhere may come from a wildcard import.
+		return cg.resolveWildcardImport(file, name)
+	}
+
+	var defs []flowValue
+	for _, decl := range decls {
+		if decl == at {
+			// Parameters are declared by their own identifiers, and their values are
+			// found by the value flow analysis. An annotated parameter may also be any
+			// instance of its annotated type.
+			defs = append(defs, cg.resolveParameter(file, at)...)
+			defs = append(defs, cg.annotatedInstance(file, file.TypeAnnotationOf(at))...)
+		} else if file.IsImport(decl) {
+			defs = append(defs, cg.resolveImport(file, decl, name)...)
+		} else {
+			defs = append(defs, flowValue{file: file, node: decl})
+		}
+	}
+
+	return defs
+}
+
+// asValues wraps a single resolved node in a slice, which is empty if the node is nil.
+func asValues(file ParsedFile, node *sitter.Node) []flowValue {
+	if node == nil {
+		return nil
+	}
+
+	return []flowValue{{file: file, node: node}}
 }
 
 // TODO: test this very very very thoroughly
 
-// resolveDottedExpr takes a dotted expression node, and returns
-// the function definition or class/object node that it is bound to (if any could be found).
-func (cg *CallGraph) resolveDottedExpr(file ParsedFile, dottedExpr *sitter.Node) (ParsedFile, *sitter.Node) {
+// resolveDottedExpr takes a dotted expression node, and returns the function
+// definitions or class/object nodes that it may be bound to (if any could be found).
+func (cg *CallGraph) resolveDottedExpr(file ParsedFile, dottedExpr *sitter.Node) []flowValue {
 	object, property := file.GetObjectAndProperty(dottedExpr)
 
 	if object == nil || property == nil || property.Type() != "identifier" {
-		return nil, nil
+		return nil
 	}
 
-	nextFile, def := cg.resolveExpr(file, object)
-	if nextFile == nil || def == nil {
-		return nil, nil
+	// A name can be defined in terms of its own attributes (e.g: `a = a.b`),
+	// so an attribute that is already being resolved is not resolved again.
+	key := resolution{node: dottedExpr}
+	defs, resolving := cg.flow.startResolving(key)
+	if !resolving {
+		return defs
+	}
+
+	propName := property.Content(file.Module().Source)
+
+	// `super().f` looks `f` up in the classes that come after the
+	// class that `super` is called for, in that class's MRO.
+	if owner := file.SuperCallOwner(object); owner != nil {
+		for _, ownerClass := range cg.resolveExprs(file, owner) {
+			if ownerClass.file.IsClassDef(ownerClass.node) {
+				defs = append(defs, cg.lookupInMro(ownerClass.file, ownerClass.node, propName, 1)...)
+			}
+		}
+	} else {
+		for _, objectDef := range cg.resolveExprs(file, object) {
+			for _, def := range cg.resolveAttribute(objectDef.file, objectDef.node, propName) {
+				// The attributes of an object that is looked up dynamically are dynamic too
+				def.dynamic = def.dynamic || objectDef.dynamic
+				defs = append(defs, def)
+			}
+		}
+	}
+
+	cg.flow.finishResolving(key, defs)
+	return defs
+}
+
+// resolveAttribute resolves an attribute of an object (a module, class or instance) to its definitions.
+func (cg *CallGraph) resolveAttribute(file ParsedFile, def *sitter.Node, propName string) []flowValue {
+	// Methods and attributes of an instance are looked up in its class,
+	// after the attributes that its methods declare on the instance itself.
+	if classes := cg.resolveClassesOfInstance(file, def); len(classes) > 0 {
+		var defs []flowValue
+		for _, class := range classes {
+			defs = append(defs, cg.instanceAttributeOf(class.File, class.Class, propName)...)
+			defs = append(defs, cg.lookupInMro(class.File, class.Class, propName, 0)...)
+		}
+
+		return defs
+	}
+
+	if file.IsClassDef(def) {
+		return cg.lookupInMro(file, def, propName, 0)
 	}
 
 	if !slices.Contains(ScopeNodeTypes, def.Type()) {
-		return nil, nil
+		return nil
 	}
 
-	scope := nextFile.Module().ScopeOfNode[def]
+	scope := file.Module().ScopeOfNode[def]
 	if scope == nil {
-		return nil, nil
+		return nil
 	}
 
-	propName := property.Content(file.Module().Source)
-	decl := scope.Symbols[propName]
-	if decl == nil {
+	decls := scope.Definitions[propName]
+	if len(decls) == 0 {
+		if def == file.Module().Ast {
+			return cg.resolveUndefinedModuleAttribute(file, propName)
+		}
+		return nil
+	}
+
+	var defs []flowValue
+	for _, decl := range decls {
+		if file.IsImport(decl) {
+			defs = append(defs, cg.resolveImport(file, decl, propName)...)
+		} else {
+			defs = append(defs, flowValue{file: file, node: decl})
+		}
+	}
+
+	return defs
+}
+
+// resolveClassesOfInstance returns the definitions of the classes that `node` may be an instance of,
+// when `node` is a call to the constructor of a known class (e.g: `requests.Session()`),
+// the receiver parameter of a method (e.g: `self`), or a type annotation (e.g: `Session` in
+// `def f(s: Session)`). An annotation with more than one type is an instance of any of the
+// ones that can be resolved, and a constructor call is an instance of every class that its
+// callee may be (e.g: a class defined in both branches of an `if`).
+func (cg *CallGraph) resolveClassesOfInstance(file ParsedFile, node *sitter.Node) []classRef {
+	if class := file.ReceiverClassOf(node); class != nil {
+		return []classRef{{File: file, Class: class}}
+	}
+
+	if typeNames := file.AnnotatedTypesOf(node); len(typeNames) > 0 {
+		return cg.classesOfAnnotation(file, node, typeNames)
+	}
+
+	if !file.IsCallExpr(node) {
+		return nil
+	}
+
+	// Instances can be defined in terms of each other (e.g: `a = b.f()` and `b = a.g()`),
+	// so a constructor call that is already being resolved is not resolved again.
+	key := resolution{node: node, classes: true}
+	classDefs, resolving := cg.flow.startResolving(key)
+	if resolving {
+		if callee := file.GetCallee(node); callee != nil {
+			for _, def := range cg.resolveExprs(file, callee) {
+				if def.file.IsClassDef(def.node) {
+					classDefs = append(classDefs, flowValue{file: def.file, node: def.node})
+				}
+			}
+		}
+
+		cg.flow.finishResolving(key, classDefs)
+	}
+
+	var classes []classRef
+	for _, def := range classDefs {
+		classes = append(classes, classRef{File: def.file, Class: def.node})
+	}
+
+	return classes
+}
+
+// resolveSubmodule resolves a sub-module of the package `file` to its module node.
+func (cg *CallGraph) resolveSubmodule(file ParsedFile, name string) (ParsedFile, *sitter.Node) {
+	filePath := file.FilePathOfSubmodule(name)
+	if filePath == nil {
 		return nil, nil
 	}
 
-	if nextFile.IsImport(decl) {
-		return cg.resolveImport(nextFile, decl, propName)
+	submodule := cg.parseImportedFile(file, *filePath)
+	if submodule == nil {
+		return nil, nil
 	}
 
-	return nextFile, decl
+	return submodule, submodule.Module().Ast
 }
 
-// resolveCallExpr takes a call expression node, and
-// returns the function definition for the callee.
-func (cg *CallGraph) resolveCallExpr(file ParsedFile, callExpr *sitter.Node) (ParsedFile, *sitter.Node) {
+// resolveCallees takes a call expression node, and
+// returns the function definitions that it may call.
+func (cg *CallGraph) resolveCallees(file ParsedFile, callExpr *sitter.Node) []flowValue {
 	scope := GetScope(file.Module(), callExpr)
 	if scope == nil {
-		return nil, nil
+		return nil
 	}
 
 	callee := file.GetCallee(callExpr)
 	if callee == nil {
-		return nil, nil
+		return nil
 	}
 
-	file, decl := cg.resolveExpr(file, callee)
-	if file.IsFunctionDef(decl) || file.IsImport(decl) {
-		return file, decl
-	} else {
-		return file, file.FunctionDefFromNode(decl)
+	var callees []flowValue
+	for _, def := range cg.resolveExprs(file, callee) {
+		defFile, decl := def.file, def.node
+		var targets []flowValue
+		if defFile.IsFunctionDef(decl) || defFile.IsImport(decl) {
+			targets = asValues(defFile, decl)
+		} else if defFile.IsClassDef(decl) {
+			targets = cg.constructorsOf(defFile, decl)
+		} else if methods := cg.methodsOfInstance(defFile, decl, "__call__"); len(methods) > 0 {
+			// Calling an instance calls the `__call__` method of its class
+			targets = methods
+		} else {
+			targets = asValues(defFile, defFile.FunctionDefFromNode(decl))
+		}
+
+		for _, target := range targets {
+			target.dynamic = def.dynamic
+			callees = append(callees, target)
+		}
 	}
+
+	return callees
 }
 
 // ToDotGraph converts a CallGraph to a dot graph for debugging/visualization
@@ -327,41 +857,43 @@ func (cgNode *CgNode) ToDotNode(cg *CallGraph,
 
 	for _, neighbor := range cgNode.Neighbors {
 		newNode := neighbor.ToDotNode(cg, g, visited)
-		g.Edge(current, newNode)
+		edge := g.Edge(current, newNode)
+		if kind := cgNode.EdgeKindOf(neighbor); kind != EdgeCall {
+			edge.Attr("style", "dashed").Label(kind.String())
+		}
 	}
 
 	return current
 }
 
-type WalkFn func(*CgNode, []*CgNode)
-
-func (callGraph *CallGraph) Walk(fromFile string, visitFn WalkFn) {
-	visited := make(map[*CgNode]struct{})
-
-	fromFile, err := filepath.Abs(fromFile)
-	if err != nil {
-		panic(err)
-	}
-
-	var path []*CgNode
-	for _, root := range callGraph.CallGraphOfNode {
-		if root == nil {
-			panic("impossible")
-		}
-
-		if root.File.Module().FileName != fromFile {
-			continue
+// WalkFn is called with every call graph node that is reachable from an entrypoint,
+// and the path of nodes that reaches it (starting at the entrypoint's root).
+type WalkFn func(entrypoint *Entrypoint, cgNode *CgNode, path []*CgNode)
+
+// Walk visits every node that is reachable from the entrypoints added with `AddEntrypoint`,
+// in the order that they were added. Each entrypoint is walked separately, so a node that
+// is reachable from many entrypoints is visited once for each of them.
+func (callGraph *CallGraph) Walk(visitFn WalkFn) {
+	for i := range callGraph.entrypoints {
+		entrypoint := &callGraph.entrypoints[i].entrypoint
+		visitEntrypointNode := func(cgNode *CgNode, path []*CgNode) {
+			visitFn(entrypoint, cgNode, path)
 		}
 
-		if _, alreadyVisited := visited[root]; !alreadyVisited {
-			path = append(path, root)
-			root.walk(visited, &path, visitFn)
-			path = path[:len(path)-1]
+		visited := make(map[*CgNode]struct{})
+		var path []*CgNode
+		for _, root := range callGraph.entrypoints[i].roots {
+			if _, alreadyVisited := visited[root]; !alreadyVisited {
+				visited[root] = struct{}{}
+				path = append(path, root)
+				root.walk(visited, &path, visitEntrypointNode)
+				path = path[:len(path)-1]
+			}
 		}
 	}
 }
 
-func (cgNode *CgNode) walk(visited map[*CgNode]struct{}, path *[]*CgNode, fn WalkFn) {
+func (cgNode *CgNode) walk(visited map[*CgNode]struct{}, path *[]*CgNode, fn func(*CgNode, []*CgNode)) {
 	*path = append(*path, cgNode)
 	fn(cgNode, *path)
 
@@ -379,58 +911,148 @@ func (cgNode *CgNode) walk(visited map[*CgNode]struct{}, path *[]*CgNode, fn Wal
 	*path = (*path)[:len(*path)-1]
 }
 
-func (cg *CallGraph) cgNodeFromImport(file ParsedFile, defNode *sitter.Node, calleeName string) *CgNode {
-	importedFile, defInImportedFile := cg.resolveImport(file, defNode, calleeName)
-	return cg.traverseFunction(importedFile, defInImportedFile)
+func (cg *CallGraph) cgNodesFromImport(file ParsedFile, defNode *sitter.Node, calleeName string) []*CgNode {
+	var cgNodes []*CgNode
+	for _, def := range cg.resolveImport(file, defNode, calleeName) {
+		cgNodes = append(cgNodes, cg.traverseFunction(def.file, def.node))
+	}
+
+	return cgNodes
+}
+
+// parseImportedFile parses a file that is imported by `file`,
+// or returns it from the module cache if it was parsed before.
+func (cg *CallGraph) parseImportedFile(file ParsedFile, filePath string) ParsedFile {
+	// Check if the module is already imported
+	importedFile, exists := cg.ModuleCache[filePath]
+	if exists {
+		return importedFile
+	}
+
+	importedFile, err := file.ParseImportedFile(filePath)
+	if err != nil {
+		// TODO: return error when file parse fails.
+		return nil
+	}
+
+	cg.ModuleCache[filePath] = importedFile
+	return importedFile
 }
 
-func (cg *CallGraph) resolveImport(file ParsedFile, importStmt *sitter.Node, calleeName string) (ParsedFile, *sitter.Node) {
-	// 1. Resolve the import to a file path
+// resolveImport resolves a name bound by an import statement (`name`)
+// to its definitions in the imported file.
+func (cg *CallGraph) resolveImport(file ParsedFile, importStmt *sitter.Node, name string) []flowValue {
+	// 1. Resolve the imported name to a file path
 	// 2. Parse the file into a Language.Module struct
-	// 3. Find the function definition in the module that the import resolves to
-	// 4. Create a call graph for that node.
+	// 3. Find the function definitions in the module that the import resolves to
+	// 4. Create a call graph for those nodes.
+
+	// A package can import its own submodules (e.g: `from . import b` in `a/__init__.py`), which
+	// binds the name in the package that is being looked up. The submodule is imported instead.
+	key := ImportedName{Node: importStmt, Name: name}
+	if depth, inProgress := cg.importLookups[key]; inProgress {
+		cg.flow.leaveOut(depth)
+		return nil
+	}
+
+	cg.importLookups[key] = cg.flow.depth()
+	defer delete(cg.importLookups, key)
 
 	// Resolve the import to a file.
-	filePath := file.FilePathOfImport(importStmt)
-	if filePath == nil {
-		return nil, nil
+	target := file.ImportTargetOf(importStmt, name)
+	if target == nil {
+		return nil
+	}
+
+	var importedFile ParsedFile
+	if target.FilePath != "" {
+		importedFile = cg.parseImportedFile(file, target.FilePath)
 	}
 
-	// Check if the module is already importedFile
-	importedFile, exists := cg.ModuleCache[*filePath]
-	if !exists {
-		var err error
-		importedFile, err = ParseFile(file.Module().Language, *filePath)
-		if err != nil {
-			// TODO: return error when file parse fails.
-			return nil, nil
+	if target.Symbol == "" {
+		if importedFile == nil {
+			return nil
 		}
-		cg.ModuleCache[*filePath] = importedFile
+		return asValues(importedFile, importedFile.Module().Ast)
 	}
 
-	if file.IsModuleImport(importStmt) {
-		return importedFile, importedFile.Module().Ast
+	// Find the function definitions in the module
+	if importedFile != nil {
+		if defs := cg.resolveSymbolInModule(importedFile, target.Symbol); len(defs) > 0 {
+			return defs
+		}
 	}
 
-	// Find the function definition in the module
-	def := importedFile.ResolveExportedSymbol(calleeName)
-	if def == nil {
-		return nil, nil
+	// The module doesn't define the symbol, so it must be a submodule.
+	if target.SubmodulePath == "" {
+		return nil
 	}
 
-	// TODO: what if its an expr?
-	// e.g: foo = "bar" # py
-	// e.g: export const foo = "bar" // js
-	// in this case, also handle recursive imports :<
+	submodule := cg.parseImportedFile(file, target.SubmodulePath)
+	if submodule == nil {
+		return nil
+	}
+	return asValues(submodule, submodule.Module().Ast)
+}
+
+// resolveSymbolInModule finds the definitions of a symbol exported by `file`,
+// following imports and wildcard imports that re-export it from other modules.
+func (cg *CallGraph) resolveSymbolInModule(file ParsedFile, symbol string) []flowValue {
+	decls := file.ResolveExportedSymbol(symbol)
+	if len(decls) == 0 {
+		return cg.resolveWildcardImport(file, symbol)
+	}
+
+	var defs []flowValue
+	for _, decl := range decls {
+		if file.IsImport(decl) {
+			defs = append(defs, cg.resolveImport(file, decl, symbol)...)
+			continue
+		}
 
-	if importedFile.IsImport(def) {
-		return cg.resolveImport(importedFile, def, calleeName)
+		// Classes and other expressions are returned as-is, so that callers can tell
+		// a class from its constructor (e.g: to look up methods on `Session` in `Session().get()`).
+		defs = append(defs, flowValue{file: file, node: decl})
 	}
 
-	// TODO: check for infinite loops
-	if !importedFile.IsFunctionDef(def) {
-		def = importedFile.FunctionDefFromNode(def)
+	return defs
+}
+
+// resolveWildcardImport resolves `name` to its definitions in one of the modules that
+// `file` imports with a wildcard import (e.g: `from a import *` in python).
+// Wildcard imports are tried in source order, and the first module that exports `name` wins.
+func (cg *CallGraph) resolveWildcardImport(file ParsedFile, name string) []flowValue {
+	// Packages can re-export each other's names with wildcard
+	// imports in a cycle, so every lookup is only tried once at a time.
+	key := wildcardLookup{filePath: file.Module().FileName, name: name}
+	if depth, inProgress := cg.wildcardLookups[key]; inProgress {
+		cg.flow.leaveOut(depth)
+		return nil
+	}
+
+	cg.wildcardLookups[key] = cg.flow.depth()
+	defer delete(cg.wildcardLookups, key)
+
+	for _, importStmt := range file.Module().GlobalScope.WildcardImports {
+		target := file.ImportTargetOf(importStmt, WildcardName)
+		if target == nil {
+			continue
+		}
+
+		importedFile := cg.parseImportedFile(file, target.FilePath)
+		if importedFile == nil || !importedFile.ExportsToWildcard(name) {
+			continue
+		}
+
+		if defs := cg.resolveSymbolInModule(importedFile, name); len(defs) > 0 {
+			return defs
+		}
+
+		// A name listed in `__all__` can also be a submodule of the package.
+		if submodule, ast := cg.resolveSubmodule(importedFile, name); ast != nil {
+			return asValues(submodule, ast)
+		}
 	}
 
-	return importedFile, def
+	return nil
 }
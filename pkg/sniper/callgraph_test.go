@@ -1,9 +1,13 @@
 package sniper
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/srijanpaul-deepsource/reachable/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,6 +27,28 @@ func removeWhitespace(s string) string {
 	return strings.Join(strings.Fields(s), "")
 }
 
+// callGraphOfMain writes the files of a project to a directory named `project`, and
+// returns the call graph of the call to `main` in its `main.py`, without whitespace.
+func callGraphOfMain(t *testing.T, files map[string]string) string {
+	projectRoot := filepath.Join(t.TempDir(), "project")
+	testutil.WriteFiles(t, projectRoot, files)
+
+	mainDotPy := filepath.Join(projectRoot, "main.py")
+	contents, err := os.ReadFile(mainDotPy)
+	require.NoError(t, err)
+
+	py, err := ParsePython(mainDotPy, contents)
+	require.NoError(t, err)
+
+	dg := DotGraphFromTsQuery(
+		`(call function:(identifier) @id (.match? @id "main")) @call`,
+		py,
+	)
+	require.NotNil(t, dg)
+
+	return removeWhitespace(dg.String())
+}
+
 func Test_CallGraph(t *testing.T) {
 	py, err := ParsePython("test.py", []byte(code))
 	require.NoError(t, err)
@@ -122,3 +148,1398 @@ foo()
 
 	assert.Equal(t, want, got)
 }
+
+func Test_CallGraphOfMain(t *testing.T) {
+	cases := map[string]struct {
+		files map[string]string
+		want  string
+	}{
+		"from imported submodules": {
+			files: map[string]string{
+				"setup.py":        "",
+				"pkg/__init__.py": "",
+				"pkg/sub_a.py":    "def run():\n\tpass\n",
+				"pkg/sub_b.py":    "def run():\n\tpass\n",
+				"main.py": `
+from pkg import sub_a, sub_b
+
+def main():
+	sub_a.run()
+	sub_b.run()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::pkg/sub_a:run"];
+				n3[label="project::pkg/sub_b:run"];
+				n1->n2;
+				n1->n3;
+			}`,
+		},
+		"dotted imports": {
+			files: map[string]string{
+				"setup.py":                 "",
+				"urllib3/__init__.py":      "",
+				"urllib3/util/__init__.py": "",
+				"urllib3/util/retry.py":    "class Retry:\n\tdef __init__(self):\n\t\tpass\n",
+				"xml/__init__.py":          "",
+				"xml/etree/__init__.py":    "",
+				"xml/etree/ElementTree.py": "def parse(source):\n\tpass\n",
+				"main.py": `
+import os, urllib3.util
+import xml.etree.ElementTree as ET
+
+def main():
+	urllib3.util.retry.Retry()
+	ET.parse("x.xml")
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::urllib3/util/retry:__init__"];
+				n3[label="project::xml/etree/ElementTree:parse"];
+				n1->n2;
+				n1->n3;
+			}`,
+		},
+		"wildcard imports": {
+			files: map[string]string{
+				"setup.py": "",
+				// `lib` re-exports the names of `lib.impl`, and `lib.cycle` re-exports `lib` back.
+				// `_setup` is in the `__all__` of `lib.impl`, but `lib` itself doesn't re-export private names.
+				"lib/__init__.py": "from .impl import *\nfrom .cycle import *\n",
+				"lib/cycle.py":    "from . import *\n",
+				"lib/impl.py": `
+__all__ = ["run", "_setup"]
+
+def run():
+	pass
+
+def _setup():
+	pass
+
+def hidden():
+	pass
+`,
+				"main.py": `
+import lib
+from lib import *
+
+def main():
+	run()
+	_setup()
+	hidden()
+	lib.run()
+	missing()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::lib/impl:run"];
+				n3[label="(unresolved):_setup"];
+				n4[label="(unresolved):hidden"];
+				n5[label="(unresolved):missing"];
+				n1->n2;
+				n1->n3;
+				n1->n4;
+				n1->n2;
+				n1->n5;
+			}`,
+		},
+		"methods of instances": {
+			files: map[string]string{
+				"setup.py":             "",
+				"requests/__init__.py": "from .sessions import Session\n",
+				"requests/sessions.py": "class Session:\n\tdef get(self, url):\n\t\tpass\n",
+				"main.py": `
+import requests
+
+class Client:
+	def send(self):
+		pass
+
+def main():
+	s = requests.Session()
+	s.get("https://example.com")
+	client = Client()
+	client.send()
+	Client().send()
+	client.missing()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::main:(unresolved)"];
+				n3[label="project::requests/sessions:get"];
+				n4[label="(unresolved):Client"];
+				n5[label="project::main:send"];
+				n6[label="project::main:(unresolved)"];
+				n1->n2;
+				n1->n3;
+				n1->n4;
+				n1->n5;
+				n1->n5;
+				n1->n4;
+				n1->n6;
+			}`,
+		},
+		"inheritance": {
+			files: map[string]string{
+				"setup.py":         "",
+				"base/__init__.py": "from .adapters import HTTPAdapter\n",
+				"base/adapters.py": `
+class BaseAdapter:
+	def __init__(self):
+		pass
+
+	def close(self):
+		pass
+
+class HTTPAdapter(BaseAdapter):
+	def send(self, request):
+		pass
+`,
+				"main.py": `
+from base import HTTPAdapter
+
+class RetryAdapter(HTTPAdapter):
+	def send(self, request):
+		super().send(request)
+
+class A:
+	def f(self):
+		pass
+
+class B(A):
+	pass
+
+class C(A):
+	def f(self):
+		overridden()
+
+def overridden():
+	pass
+
+class D(B, C, metaclass=type):
+	pass
+
+def main():
+	adapter = RetryAdapter()
+	adapter.send(None)
+	adapter.close()
+	D().f()
+
+main()
+`,
+			},
+			// The MRO of `D` is D, B, C, A, so `D().f()` calls `C.f`.
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::base/adapters:__init__"];
+				n3[label="project::main:send"];
+				n4[label="project::base/adapters:send"];
+				n5[label="(unresolved):super"];
+				n6[label="project::base/adapters:close"];
+				n7[label="project::main:f"];
+				n8[label="project::main:overridden"];
+				n9[label="(unresolved):D"];
+				n1->n2;
+				n1->n3;
+				n1->n6;
+				n1->n7;
+				n1->n9;
+				n3->n4;
+				n3->n5;
+				n7->n8;
+			}`,
+		},
+		"value flow": {
+			files: map[string]string{
+				"setup.py":             "",
+				"requests/__init__.py": "from .sessions import Session\n",
+				"requests/sessions.py": "class Session:\n\tdef get(self, url):\n\t\tpass\n",
+				"main.py": `
+import requests
+
+def make():
+	return requests.Session()
+
+def worker():
+	pass
+
+def cleanup():
+	pass
+
+def pick():
+	return worker
+
+def run(fn):
+	fn()
+
+def call_later(*, callback=cleanup):
+	callback()
+
+def main():
+	make().get("https://example.com")
+	job = worker
+	run(job)
+	call_later()
+	pick()()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::requests/sessions:get"];
+				n3[label="project::main:make"];
+				n4[label="project::main:(unresolved)"];
+				n5[label="project::main:run"];
+				n6[label="project::main:worker"];
+				n7[label="project::main:call_later"];
+				n8[label="project::main:cleanup"];
+				n9[label="project::main:pick"];
+				n1->n2;
+				n1->n3;
+				n1->n5;
+				n1->n7;
+				n1->n6;
+				n1->n9;
+				n3->n4;
+				n5->n6;
+				n7->n8;
+			}`,
+		},
+		"self references": {
+			files: map[string]string{
+				"setup.py": "",
+				// `pkg` imports its own submodule, which binds `tasks` in `pkg` itself.
+				"pkg/__init__.py": "from . import tasks\n",
+				"pkg/tasks.py":    "def run():\n\tpass\n",
+				"main.py": `
+import pkg
+
+node = node.parent
+
+def visit(fn=fn):
+	fn()
+
+def main():
+	node.parent.walk()
+	visit()
+	pkg.tasks.run()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::main:(unresolved)"];
+				n3[label="project::main:visit"];
+				n4[label="(unresolved):fn"];
+				n5[label="project::pkg/tasks:run"];
+				n1->n2;
+				n1->n3;
+				n1->n5;
+				n3->n4;
+			}`,
+		},
+		"module initializer": {
+			files: map[string]string{
+				"setup.py":            "",
+				"plugins/__init__.py": "from .registry import register\n\nregister()\n",
+				"plugins/registry.py": "def register():\n\tpass\n",
+				"main.py": `
+def main():
+	import plugins
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::plugins/__init__:<module>"];
+				n3[label="project::plugins/registry:<module>"];
+				n4[label="project::plugins/registry:register"];
+				n1->n2[label="import",style="dashed"];
+				n2->n3[label="import",style="dashed"];
+				n2->n4;
+			}`,
+		},
+		"protocols": {
+			files: map[string]string{
+				"setup.py": "",
+				"lib.py": `
+class File:
+	def __enter__(self):
+		pass
+
+	def __exit__(self, *exc):
+		pass
+
+	def __iter__(self):
+		return self
+
+	def __next__(self):
+		pass
+
+	def __getitem__(self, key):
+		pass
+
+	def __add__(self, other):
+		pass
+
+	def __call__(self):
+		pass
+
+	@property
+	def name(self):
+		pass
+
+	def close(self):
+		pass
+
+def open():
+	return File()
+`,
+				"main.py": `
+import lib
+
+def main():
+	f = lib.open()
+	with f:
+		for line in f:
+			pass
+	f["key"]
+	f + f
+	f()
+	f.name
+	f.close
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::lib:open"];
+				n3[label="(unresolved):File"];
+				n4[label="project::lib:__enter__"];
+				n5[label="project::lib:__exit__"];
+				n6[label="project::lib:__iter__"];
+				n7[label="project::lib:__next__"];
+				n8[label="project::lib:__getitem__"];
+				n9[label="project::lib:__add__"];
+				n10[label="project::lib:__call__"];
+				n11[label="project::lib:name"];
+				n1->n2;
+				n1->n4;
+				n1->n5;
+				n1->n6;
+				n1->n7;
+				n1->n8;
+				n1->n9;
+				n1->n10;
+				n1->n11;
+				n2->n3;
+			}`,
+		},
+		"reaching definitions": {
+			files: map[string]string{
+				"setup.py":    "",
+				"fastjson.py": "def loads(s):\n\tpass\n",
+				"slowjson.py": "def loads(s):\n\tpass\n",
+				"compat.py":   "try:\n\timport fastjson as json\nexcept ImportError:\n\timport slowjson as json\n",
+				"handlers.py": "def on_v2():\n\tpass\n\ndef on_v3():\n\tpass\n",
+				"main.py": `
+import sys
+from compat import json
+import handlers
+
+if sys.version_info >= (3,):
+	def handle():
+		handlers.on_v3()
+else:
+	def handle():
+		handlers.on_v2()
+
+def main():
+	json.loads("{}")
+	handle()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::fastjson:loads"];
+				n3[label="project::slowjson:loads"];
+				n4[label="project::main:handle"];
+				n5[label="project::handlers:on_v3"];
+				n6[label="project::main:handle"];
+				n7[label="project::handlers:on_v2"];
+				n1->n2;
+				n1->n3;
+				n1->n4;
+				n1->n6;
+				n4->n5;
+				n6->n7;
+			}`,
+		},
+		"every definition of a name": {
+			files: map[string]string{
+				"setup.py": "",
+				"shapes.py": `
+import sys
+
+if sys.platform == "win32":
+	class Shape:
+		def __init__(self):
+			pass
+
+		def draw(self):
+			pass
+else:
+	class Shape:
+		def __init__(self):
+			pass
+
+		def draw(self):
+			pass
+
+class Canvas:
+	if sys.platform == "win32":
+		def clear(self):
+			pass
+	else:
+		def clear(self):
+			pass
+
+def make():
+	return _registry[0]
+`,
+				"shapes.pyi": `
+class Shape:
+	def draw(self) -> None: ...
+
+def make() -> Shape: ...
+`,
+				"lazy.py": `
+import sys
+
+if sys.version_info >= (3, 7):
+	def __getattr__(name):
+		pass
+else:
+	def __getattr__(name):
+		pass
+`,
+				"exports.py": `
+__all__ = ["first"]
+__all__ += ["second"]
+
+def first():
+	pass
+
+def second():
+	pass
+`,
+				"a.py": "class Base:\n\tdef run(self):\n\t\tpass\n",
+				"b.py": "class Base:\n\tdef run(self):\n\t\tpass\n",
+				"jobs.py": `
+try:
+	from a import Base
+except ImportError:
+	from b import Base
+
+class Child(Base):
+	pass
+`,
+				"main.py": `
+import shapes
+import lazy
+import jobs
+from exports import *
+
+def main():
+	shape = shapes.Shape()
+	shape.draw()
+	shapes.Canvas().clear()
+	shapes.make().draw()
+	lazy.anything()
+	second()
+	jobs.Child().run()
+
+main()
+`,
+			},
+			// Every definition of a name is reached, including the ones that are only
+			// found through the stub of a module, or added to `__all__` later on, and
+			// the methods of every class that a base class may be.
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::shapes:__init__"];
+				n3[label="project::shapes:__init__"];
+				n4[label="project::shapes:draw"];
+				n5[label="project::shapes:draw"];
+				n6[label="project::shapes:clear"];
+				n7[label="project::shapes:clear"];
+				n8[label="project::main:(unresolved)"];
+				n9[label="project::shapes:make"];
+				n10[label="project::main:(unresolved)"];
+				n11[label="project::lazy:__getattr__"];
+				n12[label="project::lazy:__getattr__"];
+				n13[label="project::exports:second"];
+				n14[label="project::a:run"];
+				n15[label="project::b:run"];
+				n16[label="project::main:(unresolved)"];
+				n1->n2;
+				n1->n3;
+				n1->n4;
+				n1->n5;
+				n1->n6;
+				n1->n7;
+				n1->n8;
+				n1->n4;
+				n1->n5;
+				n1->n9;
+				n1->n10;
+				n1->n11[label="dynamic",style="dashed"];
+				n1->n12[label="dynamic",style="dashed"];
+				n1->n13;
+				n1->n14;
+				n1->n15;
+				n1->n16;
+			}`,
+		},
+		"scoping": {
+			files: map[string]string{
+				"setup.py": "",
+				"main.py": `
+def log():
+	pass
+
+def noop():
+	pass
+
+def audit():
+	pass
+
+hook = noop
+
+class Job:
+	log = audit
+
+	def run(self):
+		log()
+
+def install():
+	global hook
+	hook = audit
+
+def main():
+	Job().run()
+	hook()
+	apply = lambda fn: fn()
+	apply(noop)
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::main:run"];
+				n3[label="project::main:log"];
+				n4[label="(unresolved):Job"];
+				n5[label="project::main:noop"];
+				n6[label="project::main:audit"];
+				n7[label="project::main:apply"];
+				n1->n2;
+				n1->n4;
+				n1->n5;
+				n1->n6;
+				n1->n7;
+				n2->n3;
+				n7->n5;
+			}`,
+		},
+		"binding forms": {
+			files: map[string]string{
+				"setup.py": "",
+				"lib.py": `
+class Connection:
+	def __enter__(self):
+		return self
+
+	def __exit__(self, *exc):
+		pass
+
+	def send(self):
+		pass
+
+def connect():
+	return Connection()
+
+def parse():
+	pass
+
+def fallback():
+	pass
+`,
+				"main.py": `
+import lib
+
+def main():
+	with lib.connect() as conn:
+		conn.send()
+	if (parser := lib.parse):
+		parser()
+	first, (second, _) = lib.parse, (lib.fallback, None)
+	second()
+	match lib.fallback:
+		case handler:
+			handler()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::lib:__enter__"];
+				n3[label="project::lib:__exit__"];
+				n4[label="project::lib:connect"];
+				n5[label="(unresolved):Connection"];
+				n6[label="project::lib:send"];
+				n7[label="project::lib:parse"];
+				n8[label="project::lib:fallback"];
+				n1->n2;
+				n1->n3;
+				n1->n4;
+				n1->n6;
+				n1->n7;
+				n1->n8;
+				n1->n8;
+				n4->n5;
+			}`,
+		},
+		"type annotations": {
+			files: map[string]string{
+				"setup.py": "",
+				"lib.py": `
+SESSIONS = []
+
+class Client:
+	def get(self):
+		pass
+
+class Session:
+	def post(self):
+		pass
+
+	def close(self):
+		pass
+
+	def reset(self):
+		pass
+
+def make() -> "Session":
+	return SESSIONS[0]
+`,
+				"main.py": `
+from typing import Optional
+import lib
+from lib import Session
+
+class Service:
+	def __init__(self, factory):
+		self.session: Session = factory()
+
+	def stop(self):
+		self.session.close()
+
+def handle(client: lib.Client, session: "Session", fallback: Optional[Session] = None, *args: lib.Client):
+	client.get()
+	session.post()
+	fallback.post()
+	lib.make().reset()
+	current: Session | None = None
+	current.close()
+	Service(lib.make).stop()
+
+def main():
+	handle()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::main:handle"];
+				n3[label="project::lib:get"];
+				n4[label="project::lib:post"];
+				n5[label="project::lib:reset"];
+				n6[label="project::lib:make"];
+				n7[label="project::lib:close"];
+				n8[label="project::main:stop"];
+				n9[label="project::main:__init__"];
+				n1->n2;
+				n2->n3;
+				n2->n4;
+				n2->n4;
+				n2->n5;
+				n2->n6;
+				n2->n7;
+				n2->n8;
+				n2->n9;
+				n8->n7;
+				n9->n6;
+			}`,
+		},
+		"instance attributes": {
+			files: map[string]string{
+				"setup.py": "",
+				"lib.py": `
+class Session:
+	def __init__(self):
+		pass
+
+	def post(self):
+		pass
+
+	def close(self):
+		pass
+
+def backoff():
+	pass
+
+def log():
+	pass
+`,
+				"main.py": `
+import lib
+
+class Client:
+	def __init__(self, retries):
+		self.session = lib.Session()
+		self.retries, self.backoff = retries, lib.backoff
+
+	def send(self):
+		self.session.post()
+		self.backoff()
+
+class Child(Client):
+	def start(self, logger):
+		self.logger = logger
+
+	def run(self):
+		self.session.close()
+		self.logger()
+
+def main():
+	Client(3).send()
+	child = Child(1)
+	child.start(lib.log)
+	child.run()
+
+main()
+`,
+			},
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::main:send"];
+				n3[label="project::lib:post"];
+				n4[label="project::lib:backoff"];
+				n5[label="project::main:__init__"];
+				n6[label="project::lib:__init__"];
+				n7[label="project::main:start"];
+				n8[label="project::main:run"];
+				n9[label="project::lib:close"];
+				n10[label="project::lib:log"];
+				n1->n2;
+				n1->n5;
+				n1->n5;
+				n1->n7;
+				n1->n8;
+				n2->n3;
+				n2->n4;
+				n5->n6;
+				n8->n9;
+				n8->n10;
+			}`,
+		},
+		"dynamic lookups": {
+			files: map[string]string{
+				"setup.py":            "",
+				"plugins/__init__.py": "",
+				"plugins/csv.py":      "def load():\n\tpass\n",
+				"plugins/json.py":     "def load():\n\tpass\n\ndef dump():\n\tpass\n",
+				"lazy.py": `
+def __getattr__(name):
+	return _load
+
+def _load():
+	pass
+`,
+				"main.py": `
+import importlib
+import lazy
+
+FORMAT = "plugins.csv"
+
+def main():
+	plugin = importlib.import_module(FORMAT)
+	plugin.load()
+	json = importlib.import_module(".json", package="plugins")
+	getattr(json, "dump")()
+	__import__("plugins.json").json.load()
+	lazy.anything()
+	handler = lazy.other
+	handler()
+	getattr(json, undefined)()
+	getattr(json, "missing", fallback)()
+	getattr(json, "dump", fallback)()
+
+def fallback():
+	pass
+
+main()
+`,
+			},
+			// `importlib` can't be resolved without the standard library, but the modules that it imports can.
+			// The default of `getattr` is only reached when the attribute is missing.
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::main:(unresolved)"];
+				n3[label="project::plugins/__init__:<module>"];
+				n4[label="project::plugins/csv:<module>"];
+				n5[label="project::plugins/csv:load"];
+				n6[label="project::main:(unresolved)"];
+				n7[label="project::plugins/json:<module>"];
+				n8[label="project::plugins/json:dump"];
+				n9[label="(unresolved):getattr"];
+				n10[label="project::plugins/json:load"];
+				n11[label="(unresolved):__import__"];
+				n12[label="project::lazy:_load"];
+				n13[label="project::lazy:__getattr__"];
+				n14[label="project::main:(unresolved)"];
+				n15[label="project::main:fallback"];
+				n1->n2;
+				n1->n3[label="dynamic",style="dashed"];
+				n1->n4[label="dynamic",style="dashed"];
+				n1->n5[label="dynamic",style="dashed"];
+				n1->n6;
+				n1->n3[label="dynamic",style="dashed"];
+				n1->n7[label="dynamic",style="dashed"];
+				n1->n8[label="dynamic",style="dashed"];
+				n1->n9;
+				n1->n10[label="dynamic",style="dashed"];
+				n1->n11;
+				n1->n3[label="dynamic",style="dashed"];
+				n1->n7[label="dynamic",style="dashed"];
+				n1->n12[label="dynamic",style="dashed"];
+				n1->n13[label="dynamic",style="dashed"];
+				n1->n13[label="dynamic",style="dashed"];
+				n1->n12[label="dynamic",style="dashed"];
+				n1->n14;
+				n1->n9;
+				n1->n15[label="dynamic",style="dashed"];
+				n1->n9;
+				n1->n8[label="dynamic",style="dashed"];
+				n1->n9;
+			}`,
+		},
+		"imported module initializers": {
+			files: map[string]string{
+				"setup.py": "",
+				// The top-level code of `env` passes `_encode` to `Env` (through `_create`),
+				// and is only traversed after `Env.get` (which calls it) was traversed.
+				"env.py": `
+import codecs
+
+class Env:
+	def __init__(self, encode):
+		self.encode = encode
+
+	def get(self):
+		self.encode()
+
+def _encode():
+	pass
+
+def _create():
+	return Env(_encode)
+
+environ = _create()
+`,
+				"codecs.py": "import env\n\ndef lookup():\n\tpass\n\nlookup()\n",
+				"main.py": `
+def main():
+	import env
+	env.environ.get()
+
+main()
+`,
+			},
+			// `Env.get` was traversed before `_encode` was passed to `Env`, so it keeps its
+			// unresolved call too. `codecs` and `env` import each other, and run once.
+			want: `digraph {
+				n1[label="project::main:main"];
+				n2[label="project::env:<module>"];
+				n3[label="project::codecs:<module>"];
+				n4[label="project::codecs:lookup"];
+				n5[label="project::env:_create"];
+				n6[label="project::env:__init__"];
+				n7[label="project::env:get"];
+				n8[label="project::env:(unresolved)"];
+				n9[label="project::env:_encode"];
+				n1->n2[label="import",style="dashed"];
+				n1->n7;
+				n2->n3[label="import",style="dashed"];
+				n2->n5;
+				n3->n2[label="import",style="dashed"];
+				n3->n4;
+				n5->n6;
+				n7->n8;
+				n7->n9;
+			}`,
+		},
+	}
+
+	for name, tc := range cases {
+		assert.Equal(t, removeWhitespace(tc.want), callGraphOfMain(t, tc.files), name)
+	}
+}
+
+func Test_CallGraphSelfAndCls(t *testing.T) {
+	code := `
+class Client:
+	def __init__(self):
+		self.connect()
+
+	def connect(self):
+		self._dial()
+
+	def _dial(this):
+		pass
+
+	@classmethod
+	def create(cls):
+		cls.defaults()
+		return cls()
+
+	@classmethod
+	def defaults(cls):
+		pass
+
+	@staticmethod
+	def helper(self):
+		self.connect()
+
+def main():
+	Client.create()
+	Client.helper(None)
+
+main()
+`
+
+	py, err := ParsePython("test.py", []byte(code))
+	require.NoError(t, err)
+
+	dg := DotGraphFromTsQuery(
+		`(call function:(identifier) @id (.match? @id "main")) @call`,
+		py,
+	)
+	require.NotNil(t, dg)
+
+	want := removeWhitespace(`digraph {
+		n1[label="test:main"];
+		n2[label="test:create"];
+		n3[label="test:defaults"];
+		n4[label="test:__init__"];
+		n5[label="test:connect"];
+		n6[label="test:_dial"];
+		n7[label="test:helper"];
+		n8[label="test:(unresolved)"];
+		n1->n2;
+		n1->n7;
+		n2->n3;
+		n2->n4;
+		n4->n5;
+		n5->n6;
+		n7->n8;
+	}`)
+
+	assert.Equal(t, want, removeWhitespace(dg.String()))
+}
+
+func Test_CallGraphDecorators(t *testing.T) {
+	code := `
+def cache(fn):
+	return fn
+
+def route(path):
+	return cache
+
+class Registry:
+	def register(self, fn):
+		return fn
+
+registry = Registry()
+
+def main():
+	@cache
+	@route("/")
+	@registry.register
+	def handler():
+		helper()
+
+	handler()
+
+def helper():
+	pass
+
+main()
+`
+
+	py, err := ParsePython("test.py", []byte(code))
+	require.NoError(t, err)
+
+	dg := DotGraphFromTsQuery(
+		`(call function:(identifier) @id (.match? @id "main")) @call`,
+		py,
+	)
+	require.NotNil(t, dg)
+
+	want := removeWhitespace(`digraph {
+		n1[label="test:main"];
+		n2[label="test:cache"];
+		n3[label="test:route"];
+		n4[label="test:register"];
+		n5[label="test:handler"];
+		n6[label="test:helper"];
+		n1->n2;
+		n1->n3;
+		n1->n4;
+		n1->n5;
+		n5->n6;
+	}`)
+
+	assert.Equal(t, want, removeWhitespace(dg.String()))
+}
+
+func Test_CallGraphCallbacks(t *testing.T) {
+	code := `
+import threading
+
+def worker():
+	pass
+
+def on_start():
+	pass
+
+def on_stop():
+	pass
+
+def both():
+	pass
+
+def main():
+	threading.Thread(target=worker)
+	sorted([], key=lambda x: x)
+	register([on_start, on_stop])
+	for handler in (on_start, on_stop):
+		handler()
+	map(both, [])
+	both()
+
+main()
+`
+
+	py, err := ParsePython("test.py", []byte(code))
+	require.NoError(t, err)
+
+	cg := NewCallGraph()
+	cg.Options.Callbacks = true
+
+	// the last statement in the module is `main()`
+	ast := py.Module().Ast
+	mainCall := ast.NamedChild(int(ast.NamedChildCount()) - 1).NamedChild(0)
+	require.Equal(t, "call", mainCall.Type())
+
+	mainCgNode := cg.FindCallGraph(py, mainCall)
+	require.NotNil(t, mainCgNode)
+
+	kindOf := make(map[string]EdgeKind)
+	for _, neighbor := range mainCgNode.Neighbors {
+		if neighbor.FuncName != nil && neighbor.Func != nil {
+			kindOf[*neighbor.FuncName] = mainCgNode.EdgeKindOf(neighbor)
+		}
+	}
+
+	want := map[string]EdgeKind{
+		"worker":   EdgeCallback,
+		"":         EdgeCallback, // the lambda
+		"on_start": EdgeCallback,
+		"on_stop":  EdgeCallback,
+		"both":     EdgeCall,
+	}
+	assert.Equal(t, want, kindOf)
+
+	// Without the option, only direct calls are added.
+	withoutCallbacks := NewCallGraph().FindCallGraph(py, mainCall)
+	for _, neighbor := range withoutCallbacks.Neighbors {
+		assert.Equal(t, EdgeCall, withoutCallbacks.EdgeKindOf(neighbor))
+		if neighbor.Func != nil {
+			assert.Equal(t, "both", *neighbor.FuncName)
+		}
+	}
+}
+
+func Test_CallGraphValueFlowFromLaterCallers(t *testing.T) {
+	code := `
+def x():
+	pass
+
+def run(fn):
+	fn()
+
+def a():
+	run(x)
+
+def main():
+	run(None)
+	a()
+
+main()
+`
+
+	py, err := ParsePython("test.py", []byte(code))
+	require.NoError(t, err)
+
+	dg := DotGraphFromTsQuery(
+		`(call function:(identifier) @id (.match? @id "main")) @call`,
+		py,
+	)
+	require.NotNil(t, dg)
+
+	// `run` is traversed when `main` calls it, before `a` passes `x` to it.
+	want := removeWhitespace(`digraph {
+		n1[label="test:main"];
+		n2[label="test:run"];
+		n3[label="(unresolved):fn"];
+		n4[label="test:x"];
+		n5[label="test:a"];
+		n1->n2;
+		n1->n5;
+		n2->n3;
+		n2->n4;
+		n5->n2;
+	}`)
+
+	assert.Equal(t, want, removeWhitespace(dg.String()))
+}
+
+func Test_CallGraphValueFlowFromEveryLaterCaller(t *testing.T) {
+	code := `
+def x():
+	pass
+
+def y():
+	pass
+
+def run(fn):
+	fn()
+
+def a():
+	run(x)
+
+def b():
+	run(y)
+
+def main():
+	a()
+	b()
+
+main()
+`
+
+	py, err := ParsePython("test.py", []byte(code))
+	require.NoError(t, err)
+
+	dg := DotGraphFromTsQuery(
+		`(call function:(identifier) @id (.match? @id "main")) @call`,
+		py,
+	)
+	require.NotNil(t, dg)
+
+	// `run` is traversed when `a` calls it, before `b` passes `y` to it.
+	want := removeWhitespace(`digraph {
+		n1[label="test:main"];
+		n2[label="test:a"];
+		n3[label="test:run"];
+		n4[label="test:x"];
+		n5[label="test:y"];
+		n6[label="test:b"];
+		n1->n2;
+		n1->n6;
+		n2->n3;
+		n3->n4;
+		n3->n5;
+		n6->n3;
+	}`)
+
+	assert.Equal(t, want, removeWhitespace(dg.String()))
+}
+
+func Test_CallGraphStubs(t *testing.T) {
+	projectRoot := t.TempDir()
+	typeshed := t.TempDir()
+	testutil.WriteFiles(t, typeshed, map[string]string{
+		"stdlib/_fastjson.pyi": `
+class Document:
+	def get(self, key: str) -> object: ...
+
+def loads(s: str) -> Document: ...
+`,
+	})
+	testutil.WriteFiles(t, projectRoot, map[string]string{
+		"setup.py": "",
+		"lib.py": `
+import _speedups
+
+class Base:
+	def __init__(self):
+		pass
+
+	def close(self):
+		pass
+
+class Session(_bases()):
+	pass
+
+class Connection(_speedups.Socket):
+	def send(self):
+		pass
+
+def connect():
+	return _registry[0]
+`,
+		"lib.pyi": `
+import _speedups
+
+class Base:
+	def close(self) -> None: ...
+
+class Session(Base): ...
+
+class Connection(_speedups.Socket):
+	def send(self) -> None: ...
+
+def connect() -> Connection: ...
+`,
+		// a C extension, which only has a stub
+		"_speedups.pyi": `
+class Socket:
+	def shutdown(self) -> None: ...
+`,
+		"main.py": `
+import lib
+import _fastjson
+
+def main():
+	conn = lib.connect()
+	conn.send()
+	conn.shutdown()
+	lib.Session().close()
+	_fastjson.loads("{}").get("key")
+
+main()
+`,
+	})
+
+	mainDotPy := filepath.Join(projectRoot, "main.py")
+	contents, err := os.ReadFile(mainDotPy)
+	require.NoError(t, err)
+
+	py, err := ParsePythonWithEnv(mainDotPy, contents, PythonEnv{TypeshedPath: typeshed})
+	require.NoError(t, err)
+
+	cg := NewCallGraph()
+	mainCall := py.module.Ast.NamedChild(int(py.module.Ast.NamedChildCount()) - 1).NamedChild(0)
+	mainNode := cg.FindCallGraph(py, mainCall)
+	require.NotNil(t, mainNode)
+
+	var callees []string
+	for _, callee := range mainNode.Neighbors {
+		if callee.Func == nil {
+			callees = append(callees, fmt.Sprintf("(unresolved):%v", callee.FuncName))
+			continue
+		}
+
+		fileName, err := filepath.Rel(projectRoot, callee.File.Module().FileName)
+		if err != nil || strings.HasPrefix(fileName, "..") {
+			fileName, err = filepath.Rel(typeshed, callee.File.Module().FileName)
+			require.NoError(t, err)
+		}
+		callees = append(callees, fileName+":"+*callee.FuncName)
+	}
+
+	// Edges to functions that have source go to the source, not to the stub
+	assert.Equal(t, []string{
+		"lib.py:connect",
+		"lib.py:send",
+		"_speedups.pyi:shutdown",
+		"lib.py:close",
+		"lib.py:__init__",
+		"stdlib/_fastjson.pyi:get",
+		"stdlib/_fastjson.pyi:loads",
+	}, callees)
+}
+
+func Test_CallGraphDeepImportChain(t *testing.T) {
+	// Every module returns the values of two calls to the previous module, so the
+	// values of the last module would be resolved 2^depth times if they weren't cached.
+	const depth = 40
+
+	files := map[string]string{
+		"setup.py": "",
+		"m0.py":    "class Job:\n\tdef run(self):\n\t\tpass\n\ndef make():\n\treturn Job()\n",
+		"main.py":  fmt.Sprintf("from m%d import make\n\ndef main():\n\tmake().run()\n\nmain()\n", depth),
+	}
+	for i := 1; i <= depth; i++ {
+		files[fmt.Sprintf("m%d.py", i)] = fmt.Sprintf(`
+import m%d
+
+def make():
+	first = m%[1]d.make()
+	second = m%[1]d.make()
+	if first:
+		return first
+	return second
+`, i-1)
+	}
+
+	// Each `make` calls the `make` of the previous module twice.
+	var want strings.Builder
+	want.WriteString(`digraph {
+		n1[label="project::main:main"];
+		n2[label="project::m0:run"];`)
+	for i := depth; i >= 0; i-- {
+		fmt.Fprintf(&want, `n%d[label="project::m%d:make"];`, depth-i+3, i)
+	}
+	fmt.Fprintf(&want, `n%d[label="(unresolved):Job"];`, depth+4)
+	want.WriteString("n1->n2;n1->n3;")
+	for n := 3; n < depth+3; n++ {
+		fmt.Fprintf(&want, "n%[1]d->n%[2]d;n%[1]d->n%[2]d;", n, n+1)
+	}
+	fmt.Fprintf(&want, "n%d->n%d;}", depth+3, depth+4)
+
+	assert.Equal(t, removeWhitespace(want.String()), callGraphOfMain(t, files))
+}
@@ -0,0 +1,243 @@
+package sniper
+
+import (
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// resolveDynamicLookup resolves a call that looks a module or an attribute up by name
+// (see `ParsedFile.DynamicLookupOf`) to the modules or attributes that it may evaluate to.
+// Only names that are constant strings (or names bound to them) can be resolved.
+// The default of `getattr` is included when the attribute may be missing. The values are
+// marked dynamic. Returns nil for any other call.
+func (cg *CallGraph) resolveDynamicLookup(file ParsedFile, callExpr *sitter.Node) []flowValue {
+	if values, cached := cg.dynamicLookups[callExpr]; cached {
+		return values
+	}
+
+	lookup := file.DynamicLookupOf(callExpr)
+	if lookup == nil {
+		return nil
+	}
+
+	// A name may be computed from the value of the lookup itself, which resolves
+	// to nothing while it is being resolved (the same way as in `mroOf`).
+	cg.dynamicLookups[callExpr] = nil
+
+	var values []flowValue
+	if lookup.Object == nil {
+		for _, moduleName := range cg.dynamicModuleNames(file, lookup) {
+			if lookup.TopLevel {
+				moduleName, _, _ = strings.Cut(moduleName, ".")
+			}
+
+			if module := cg.moduleNamed(file, moduleName); module != nil {
+				values = append(values, flowValue{file: module, node: module.Module().Ast})
+			}
+		}
+	} else {
+		// The default is returned when an object has no attribute with the name,
+		// which may be the case for any object when the name isn't known.
+		names := cg.stringValuesOf(file, lookup.Name)
+		missing := len(names) == 0
+		if !missing {
+			for _, object := range cg.resolveExprs(file, lookup.Object) {
+				for _, name := range names {
+					attributes := cg.resolveAttribute(object.file, object.node, name)
+					values = append(values, attributes...)
+					missing = missing || len(attributes) == 0
+				}
+			}
+		}
+
+		if lookup.Default != nil && missing {
+			values = append(values, flowValue{file: file, node: lookup.Default})
+		}
+	}
+
+	for i := range values {
+		values[i].dynamic = true
+	}
+
+	cg.dynamicLookups[callExpr] = values
+	return values
+}
+
+// modulesRunByDynamicImport returns the modules whose top-level code runs when a call imports a
+// module by name (e.g: `a`, `a.b` and `a.b.c` for `importlib.import_module("a.b.c")` in python).
+func (cg *CallGraph) modulesRunByDynamicImport(file ParsedFile, callExpr *sitter.Node) []ParsedFile {
+	lookup := file.DynamicLookupOf(callExpr)
+	if lookup == nil || lookup.Object != nil {
+		return nil
+	}
+
+	var modules []ParsedFile
+	for _, moduleName := range cg.dynamicModuleNames(file, lookup) {
+		parts := strings.Split(moduleName, ".")
+		for i := range parts {
+			module := cg.moduleNamed(file, strings.Join(parts[:i+1], "."))
+			if module != nil && !slices.Contains(modules, module) {
+				modules = append(modules, module)
+			}
+		}
+	}
+
+	return modules
+}
+
+// dynamicModuleNames returns the absolute names of the modules that a dynamic import may import.
+func (cg *CallGraph) dynamicModuleNames(file ParsedFile, lookup *DynamicLookup) []string {
+	var packageNames []string
+	if lookup.Package != nil {
+		packageNames = cg.stringValuesOf(file, lookup.Package)
+	}
+
+	var moduleNames []string
+	for _, name := range cg.stringValuesOf(file, lookup.Name) {
+		if !strings.HasPrefix(name, ".") {
+			moduleNames = append(moduleNames, name)
+			continue
+		}
+
+		for _, packageName := range packageNames {
+			if moduleName := absoluteModuleName(name, packageName); moduleName != "" {
+				moduleNames = append(moduleNames, moduleName)
+			}
+		}
+	}
+
+	return moduleNames
+}
+
+// absoluteModuleName resolves a relative module name (like `..b`) against the package that it is
+// relative to, the same way `importlib.import_module` does. Returns "" if the name goes above
+// the top-level package.
+func absoluteModuleName(name, packageName string) string {
+	rest := strings.TrimLeft(name, ".")
+	parts := strings.Split(packageName, ".")
+	levelsUp := len(name) - len(rest) - 1
+	if packageName == "" || levelsUp >= len(parts) {
+		return ""
+	}
+
+	base := strings.Join(parts[:len(parts)-levelsUp], ".")
+	if rest == "" {
+		return base
+	}
+
+	return base + "." + rest
+}
+
+// moduleNamed parses the module with an absolute dotted name, as it is imported from `file`.
+// Returns nil if there is no such module.
+func (cg *CallGraph) moduleNamed(file ParsedFile, name string) ParsedFile {
+	filePath := file.FilePathOfModule(name)
+	if filePath == nil {
+		return nil
+	}
+
+	return cg.parseImportedFile(file, *filePath)
+}
+
+// stringValuesOf returns the constant strings that an expression may evaluate to.
+func (cg *CallGraph) stringValuesOf(file ParsedFile, expr *sitter.Node) []string {
+	var values []string
+	for _, def := range cg.resolveExprs(file, expr) {
+		if value := def.file.StringValueOf(def.node); value != nil && !slices.Contains(values, *value) {
+			values = append(values, *value)
+		}
+	}
+
+	return values
+}
+
+// resolveUndefinedModuleAttribute resolves an attribute that a module doesn't define itself:
+// a name bound by a wildcard import, a submodule, or the values returned by the module's
+// `__getattr__` function (PEP 562), which are marked dynamic.
+func (cg *CallGraph) resolveUndefinedModuleAttribute(file ParsedFile, name string) []flowValue {
+	if defs := cg.resolveWildcardImport(file, name); len(defs) > 0 {
+		return defs
+	}
+
+	// Sub-modules of a package are attributes of the package, even
+	// when they're not imported in its `__init__` file.
+	if submoduleFile, submodule := cg.resolveSubmodule(file, name); submodule != nil {
+		return asValues(submoduleFile, submodule)
+	}
+
+	var values []flowValue
+	for _, getattr := range cg.moduleGetattrsOf(file) {
+		values = append(values, cg.returnValuesOf(file, getattr)...)
+	}
+
+	for i := range values {
+		values[i].dynamic = true
+	}
+
+	return values
+}
+
+// moduleGetattrsFor returns the `__getattr__` functions of a module (PEP 562) if reading
+// the attribute `name` of the module calls them, which is when the module doesn't define
+// `name` in any other way. Returns nil otherwise.
+func (cg *CallGraph) moduleGetattrsFor(file ParsedFile, name string) []*sitter.Node {
+	getattrs := cg.moduleGetattrsOf(file)
+	if len(getattrs) == 0 || len(file.Module().GlobalScope.Definitions[name]) > 0 {
+		return nil
+	}
+
+	if len(cg.resolveWildcardImport(file, name)) > 0 {
+		return nil
+	}
+
+	if _, submodule := cg.resolveSubmodule(file, name); submodule != nil {
+		return nil
+	}
+
+	return getattrs
+}
+
+// moduleGetattrsOf returns the `__getattr__` functions that a module defines
+// (there may be more than one, e.g: in both branches of an `if`).
+func (cg *CallGraph) moduleGetattrsOf(file ParsedFile) []*sitter.Node {
+	var getattrs []*sitter.Node
+	for _, def := range file.Module().GlobalScope.Definitions["__getattr__"] {
+		if file.IsFunctionDef(def) {
+			getattrs = append(getattrs, def)
+		}
+	}
+
+	return getattrs
+}
+
+// findModuleGetattrCalls returns the call graphs of the `__getattr__` functions (PEP 562) that
+// a call runs to look its callee up in a module (like `lazy.f()` when `lazy` doesn't define `f`).
+// Attributes that are read without being called are handled by `findImplicitCalls`.
+func (cg *CallGraph) findModuleGetattrCalls(file ParsedFile, callExpr *sitter.Node) []*CgNode {
+	callee := file.GetCallee(callExpr)
+	if callee == nil {
+		return nil
+	}
+
+	object, property := file.GetObjectAndProperty(callee)
+	if object == nil || property == nil {
+		return nil
+	}
+
+	name := property.Content(file.Module().Source)
+
+	var cgNodes []*CgNode
+	for _, def := range cg.resolveExprs(file, object) {
+		if def.node != def.file.Module().Ast {
+			continue
+		}
+
+		for _, getattr := range cg.moduleGetattrsFor(def.file, name) {
+			cgNodes = append(cgNodes, cg.traverseFunction(def.file, getattr))
+		}
+	}
+
+	return cgNodes
+}
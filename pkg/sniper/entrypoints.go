@@ -0,0 +1,437 @@
+package sniper
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Entrypoint is a place where a program starts running, like a console script.
+type Entrypoint struct {
+	// Name describes the entrypoint in reports, like the name of a console script
+	// (`mytool`), or the path of a module that is run as a script.
+	Name string
+	// FilePath is the absolute path of the module that the entrypoint runs.
+	FilePath string
+	// Function is the dotted name of the object that the entrypoint calls in its module
+	// (like `main` or `Cli.run`), or "" when the module is run as a script.
+	Function string
+}
+
+// script is a script that a python project declares, like `mytool = pkg.cli:main`.
+type script struct {
+	name string
+	// objectRef is the module and function that the script calls (`pkg.cli:main`)
+	objectRef string
+}
+
+// scriptGroups are the entry point groups (in setup.py and setup.cfg) whose entry points are scripts
+var scriptGroups = []string{"console_scripts", "gui_scripts"}
+
+// FindEntrypoints finds the entrypoints of a python project:
+//   - the console and GUI scripts declared in setup.py, setup.cfg and pyproject.toml
+//   - modules with an `if __name__ == "__main__":` block, and `__main__.py` modules
+//
+// Scripts whose module can't be found in the project (or in `env`) are left out.
+func FindEntrypoints(projectRoot string, env PythonEnv) ([]Entrypoint, error) {
+	projectRoot, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	sysPath := NewSysPath(projectRoot, env)
+
+	scripts := scriptsInSetupPy(filepath.Join(projectRoot, "setup.py"))
+	scripts = append(scripts, scriptsInSetupCfg(filepath.Join(projectRoot, "setup.cfg"))...)
+
+	pyprojectScripts, err := scriptsInPyproject(filepath.Join(projectRoot, "pyproject.toml"))
+	if err != nil {
+		return nil, err
+	}
+	scripts = append(scripts, pyprojectScripts...)
+
+	var entrypoints []Entrypoint
+	for _, script := range scripts {
+		entrypoint, err := entrypointOfObjectRef(script.objectRef, sysPath)
+		if err != nil {
+			continue
+		}
+
+		entrypoint.Name = script.name
+		entrypoints = append(entrypoints, entrypoint)
+	}
+
+	scriptModules, err := findScriptModules(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(entrypoints, scriptModules...), nil
+}
+
+// ParseEntrypoint parses an entrypoint given as `module:function` (or just `module`, to run the
+// module as a script), where the module is a dotted name that is imported from `projectRoot`.
+func ParseEntrypoint(spec string, projectRoot string, env PythonEnv) (Entrypoint, error) {
+	projectRoot, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return Entrypoint{}, err
+	}
+
+	entrypoint, err := entrypointOfObjectRef(spec, NewSysPath(projectRoot, env))
+	if err != nil {
+		return Entrypoint{}, err
+	}
+
+	entrypoint.Name = spec
+	return entrypoint, nil
+}
+
+// entrypointOfObjectRef finds the module and function of an object reference, like the
+// `pkg.cli:main [extra]` in the declaration of a console script (`mytool = pkg.cli:main [extra]`).
+func entrypointOfObjectRef(objectRef string, sysPath *SysPath) (Entrypoint, error) {
+	objectRef, _, _ = strings.Cut(objectRef, "[")
+	moduleName, function, _ := strings.Cut(strings.TrimSpace(objectRef), ":")
+	moduleName, function = strings.TrimSpace(moduleName), strings.TrimSpace(function)
+	if moduleName == "" {
+		return Entrypoint{}, fmt.Errorf("invalid entrypoint: %q", objectRef)
+	}
+
+	filePath, _ := findModuleFileWithExts(sysPath.Roots, moduleName, ".py")
+	if filePath == "" {
+		return Entrypoint{}, fmt.Errorf("could not find module %s", moduleName)
+	}
+
+	return Entrypoint{FilePath: filePath, Function: function}, nil
+}
+
+// scriptsInSetupPy returns the scripts declared in the `entry_points` argument of a `setup()`
+// call in setup.py. Only literal values are read, since setup.py isn't run.
+func scriptsInSetupPy(path string) []script {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	py, err := ParsePython(path, source)
+	if err != nil {
+		return nil
+	}
+
+	var scripts []script
+	var findSetupCalls func(node *sitter.Node)
+	findSetupCalls = func(node *sitter.Node) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			findSetupCalls(child)
+			if !py.IsCallExpr(child) {
+				continue
+			}
+
+			callee := py.GetCallee(child)
+			if callee == nil || !isSetupFunction(dottedNameOf(callee, source)) {
+				continue
+			}
+
+			for _, arg := range py.ArgumentsOf(child) {
+				if arg.Keyword == "entry_points" {
+					scripts = append(scripts, py.scriptsInEntryPoints(arg.Value)...)
+				}
+			}
+		}
+	}
+
+	findSetupCalls(py.Module().Ast)
+	return scripts
+}
+
+// isSetupFunction returns `true` for the `setup` function of setuptools (or distutils).
+func isSetupFunction(name string) bool {
+	return name == "setup" || strings.HasSuffix(name, ".setup")
+}
+
+// scriptsInEntryPoints returns the scripts in the value of the `entry_points` argument of `setup()`,
+// which is either a dictionary of groups, or a string in the format of an `entry_points.txt` file.
+func (py *Python) scriptsInEntryPoints(entryPoints *sitter.Node) []script {
+	if value := py.StringValueOf(entryPoints); value != nil {
+		return scriptsInIni(strings.Split(*value, "\n"), "")
+	}
+
+	if entryPoints.Type() != "dictionary" {
+		return nil
+	}
+
+	var scripts []script
+	for _, pair := range namedChildrenExceptComments(entryPoints) {
+		key, value := pair.ChildByFieldName("key"), pair.ChildByFieldName("value")
+		if pair.Type() != "pair" || key == nil || value == nil {
+			continue
+		}
+
+		group := py.StringValueOf(key)
+		if group == nil || !isScriptGroup(*group) {
+			continue
+		}
+
+		// A group is a list of `name = object reference` strings, or a single one of them.
+		declarations := []*sitter.Node{value}
+		if elements := py.ElementsOf(value); elements != nil {
+			declarations = elements
+		}
+
+		for _, declaration := range declarations {
+			if line := py.StringValueOf(declaration); line != nil {
+				if name, objectRef, found := strings.Cut(*line, "="); found {
+					scripts = append(scripts, script{name: strings.TrimSpace(name), objectRef: strings.TrimSpace(objectRef)})
+				}
+			}
+		}
+	}
+
+	return scripts
+}
+
+// scriptsInSetupCfg returns the scripts declared in the `[options.entry_points]` section of setup.cfg.
+func scriptsInSetupCfg(path string) []script {
+	return scriptsInIni(readLines(path), "options.entry_points")
+}
+
+// scriptsInIni reads the scripts in the section `section` of an INI file (like setup.cfg),
+// where every script group is a key whose value is a list of `name = object reference` lines:
+//
+//	[options.entry_points]
+//	console_scripts =
+//	    mytool = pkg.cli:main
+//
+// When `section` is "", the lines are in the format of an `entry_points.txt` file instead, where
+// every script group is a section (`[console_scripts]`) of `name = object reference` lines.
+func scriptsInIni(lines []string, section string) []script {
+	var scripts []script
+	currentSection, group := "", ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			currentSection = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			group = ""
+			if section == "" {
+				group = currentSection
+			}
+			continue
+		}
+
+		if section != "" && currentSection != section {
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		// In setup.cfg, the indented lines are the value of the group above them.
+		isContinuation := section != "" && (line[0] == ' ' || line[0] == '\t')
+		if section != "" && !isContinuation {
+			group = key
+			if !found || value == "" {
+				continue
+			}
+			key, value, found = strings.Cut(value, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		}
+
+		if found && isScriptGroup(group) {
+			scripts = append(scripts, script{name: key, objectRef: value})
+		}
+	}
+
+	return scripts
+}
+
+// isScriptGroup returns `true` for the entry point groups that declare scripts (see `scriptGroups`).
+func isScriptGroup(group string) bool {
+	return slices.Contains(scriptGroups, group)
+}
+
+// pyproject is the part of a pyproject.toml file that declares scripts.
+type pyproject struct {
+	Project struct {
+		Scripts    map[string]string `toml:"scripts"`
+		GuiScripts map[string]string `toml:"gui-scripts"`
+	} `toml:"project"`
+	Tool struct {
+		Poetry struct {
+			// Poetry scripts are object references, or tables with a `reference`
+			Scripts map[string]any `toml:"scripts"`
+		} `toml:"poetry"`
+	} `toml:"tool"`
+}
+
+// scriptsInPyproject returns the scripts declared in the `[project.scripts]`,
+// `[project.gui-scripts]` and `[tool.poetry.scripts]` tables of pyproject.toml.
+func scriptsInPyproject(path string) ([]script, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		// A project doesn't need a pyproject.toml file
+		return nil, nil
+	}
+
+	var project pyproject
+	if err := toml.Unmarshal(contents, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var scripts []script
+	for _, table := range []map[string]string{project.Project.Scripts, project.Project.GuiScripts} {
+		for name, objectRef := range table {
+			scripts = append(scripts, script{name: name, objectRef: objectRef})
+		}
+	}
+
+	for name, value := range project.Tool.Poetry.Scripts {
+		switch value := value.(type) {
+		case string:
+			scripts = append(scripts, script{name: name, objectRef: value})
+		case map[string]any:
+			if objectRef, ok := value["reference"].(string); ok && value["type"] != "file" {
+				scripts = append(scripts, script{name: name, objectRef: objectRef})
+			}
+		}
+	}
+
+	// Tables are decoded into maps, so the scripts are sorted to keep their order stable.
+	slices.SortFunc(scripts, func(a, b script) int { return strings.Compare(a.name, b.name) })
+	return scripts, nil
+}
+
+// findScriptModules finds the modules in a project that are meant to be run as scripts: `__main__.py`
+// modules, and modules with an `if __name__ == "__main__":` block. Hidden directories and virtual
+// environments are skipped.
+func findScriptModules(projectRoot string) ([]Entrypoint, error) {
+	var entrypoints []Entrypoint
+	err := filepath.WalkDir(projectRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path == projectRoot {
+				return nil
+			}
+
+			name := entry.Name()
+			if strings.HasPrefix(name, ".") || name == "__pycache__" || name == "node_modules" ||
+				name == "site-packages" {
+				return filepath.SkipDir
+			}
+
+			if _, err := os.Stat(filepath.Join(path, "pyvenv.cfg")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".py" {
+			return nil
+		}
+
+		if entry.Name() != "__main__.py" && !hasMainBlock(path) {
+			return nil
+		}
+
+		name, _ := filepath.Rel(projectRoot, path)
+		entrypoints = append(entrypoints, Entrypoint{Name: name, FilePath: path})
+		return nil
+	})
+
+	return entrypoints, err
+}
+
+// hasMainBlock returns `true` if a python file has an `if __name__ == "__main__":` block at the top level.
+func hasMainBlock(path string) bool {
+	source, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(source), "__main__") {
+		return false
+	}
+
+	py, err := ParsePython(path, source)
+	if err != nil {
+		return false
+	}
+
+	for _, stmt := range namedChildrenExceptComments(py.Module().Ast) {
+		if stmt.Type() == "if_statement" && py.isMainCheck(stmt.ChildByFieldName("condition")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isMainCheck returns `true` for the condition `__name__ == "__main__"` (with its operands in any order).
+func (py *Python) isMainCheck(condition *sitter.Node) bool {
+	if condition == nil || condition.Type() != "comparison_operator" || condition.NamedChildCount() != 2 {
+		return false
+	}
+
+	operator := condition.ChildByFieldName("operators")
+	if operator == nil || operator.Type() != "==" {
+		return false
+	}
+
+	isName := func(node *sitter.Node) bool {
+		return node.Type() == "identifier" && node.Content(py.module.Source) == "__name__"
+	}
+
+	isMain := func(node *sitter.Node) bool {
+		value := py.StringValueOf(node)
+		return node.Type() == "string" && value != nil && *value == "__main__"
+	}
+
+	left, right := condition.NamedChild(0), condition.NamedChild(1)
+	return (isName(left) && isMain(right)) || (isMain(left) && isName(right))
+}
+
+// entrypointRoots are the call graph nodes that an entrypoint starts running from.
+type entrypointRoots struct {
+	entrypoint Entrypoint
+	roots      []*CgNode
+}
+
+// AddEntrypoint builds the call graph of everything that an entrypoint in `file` runs,
+// and adds it to the nodes that `Walk` starts from. The top-level code of the module
+// always runs, since the module is imported before its function is called. A module
+// that is run as a script (see `Entrypoint.Function`) only runs its top-level code.
+func (cg *CallGraph) AddEntrypoint(file ParsedFile, entrypoint Entrypoint) error {
+	roots := []*CgNode{cg.ModuleInitializer(file)}
+	if entrypoint.Function != "" {
+		for _, def := range cg.resolveDottedName(file, file.Module().Ast, entrypoint.Function) {
+			fns := []flowValue{def}
+			if def.file.IsClassDef(def.node) {
+				fns = cg.constructorsOf(def.file, def.node)
+			}
+
+			for _, fn := range fns {
+				if fn.file.IsFunctionDef(fn.node) {
+					roots = append(roots, cg.traverseFunction(fn.file, fn.node))
+				}
+			}
+		}
+
+		if len(roots) == 1 {
+			return fmt.Errorf(
+				"could not find function %s in %s (entrypoint %s)",
+				entrypoint.Function, file.Module().FileName, entrypoint.Name,
+			)
+		}
+	}
+
+	cg.entrypoints = append(cg.entrypoints, entrypointRoots{entrypoint: entrypoint, roots: roots})
+	return nil
+}
@@ -0,0 +1,177 @@
+package sniper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/srijanpaul-deepsource/reachable/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FindEntrypoints(t *testing.T) {
+	projectRoot := t.TempDir()
+	testutil.WriteFiles(t, projectRoot, map[string]string{
+		"setup.py": `
+from setuptools import setup
+
+setup(
+	name="tool",
+	entry_points={
+		"console_scripts": ["tool = tool.cli:main", "tool-admin=tool.admin:Admin.run [admin]"],
+		"pytest11": ["tool = tool.plugin"],
+	},
+)
+`,
+		"setup.cfg": `
+[metadata]
+name = tool
+
+[options.entry_points]
+console_scripts =
+	tool-cfg = tool.cli:main
+gui_scripts = tool-gui = tool.gui:main
+`,
+		"pyproject.toml": `
+[project.scripts]
+tool-sync = "tool.cli:sync"
+tool-missing = "not_a_module:main"
+
+[tool.poetry.scripts]
+tool-poetry = { reference = "tool.cli:main", type = "console" }
+`,
+		"tool/__init__.py":         "",
+		"tool/__main__.py":         "from .cli import main\n\nmain()\n",
+		"tool/cli.py":              "def main():\n\tpass\n\ndef sync():\n\tpass\n",
+		"tool/admin.py":            "class Admin:\n\tdef run(self):\n\t\tpass\n",
+		"tool/gui.py":              "def main():\n\tpass\n",
+		"scripts/migrate.py":       "def migrate():\n\tpass\n\nif '__main__' == __name__:\n\tmigrate()\n",
+		"scripts/helpers.py":       "if __name__ != '__main__':\n\tpass\n",
+		".venv/pyvenv.cfg":         "home = /usr/bin\n",
+		".venv/lib/script.py":      "if __name__ == '__main__':\n\tpass\n",
+		"venv/pyvenv.cfg":          "home = /usr/bin\n",
+		"venv/lib/other_script.py": "if __name__ == '__main__':\n\tpass\n",
+	})
+
+	entrypoints, err := FindEntrypoints(projectRoot, PythonEnv{})
+	require.NoError(t, err)
+
+	inProject := func(relPath string) string {
+		return filepath.Join(projectRoot, relPath)
+	}
+
+	want := []Entrypoint{
+		{Name: "tool", FilePath: inProject("tool/cli.py"), Function: "main"},
+		{Name: "tool-admin", FilePath: inProject("tool/admin.py"), Function: "Admin.run"},
+		{Name: "tool-cfg", FilePath: inProject("tool/cli.py"), Function: "main"},
+		{Name: "tool-gui", FilePath: inProject("tool/gui.py"), Function: "main"},
+		{Name: "tool-poetry", FilePath: inProject("tool/cli.py"), Function: "main"},
+		{Name: "tool-sync", FilePath: inProject("tool/cli.py"), Function: "sync"},
+		{Name: "scripts/migrate.py", FilePath: inProject("scripts/migrate.py")},
+		{Name: "tool/__main__.py", FilePath: inProject("tool/__main__.py")},
+	}
+	assert.Equal(t, want, entrypoints)
+}
+
+func Test_ParseEntrypoint(t *testing.T) {
+	projectRoot := t.TempDir()
+	testutil.WriteFiles(t, projectRoot, map[string]string{
+		"src/app/__init__.py": "",
+		"src/app/server.py":   "def serve():\n\tpass\n",
+	})
+
+	entrypoint, err := ParseEntrypoint("app.server:serve", projectRoot, PythonEnv{})
+	require.NoError(t, err)
+	assert.Equal(t, Entrypoint{
+		Name:     "app.server:serve",
+		FilePath: filepath.Join(projectRoot, "src", "app", "server.py"),
+		Function: "serve",
+	}, entrypoint)
+
+	entrypoint, err = ParseEntrypoint("app.server", projectRoot, PythonEnv{})
+	require.NoError(t, err)
+	assert.Equal(t, "", entrypoint.Function)
+
+	_, err = ParseEntrypoint("app.client:connect", projectRoot, PythonEnv{})
+	assert.Error(t, err)
+}
+
+func Test_CallGraphWalkEntrypoints(t *testing.T) {
+	projectRoot := t.TempDir()
+	testutil.WriteFiles(t, projectRoot, map[string]string{
+		"setup.py": "",
+		"lib.py": `
+def fetch():
+	pass
+
+def parse():
+	pass
+
+def render():
+	pass
+`,
+		"cli.py": `
+import lib
+
+class Cli:
+	def run(self):
+		lib.fetch()
+
+def main():
+	lib.parse()
+`,
+		"script.py": `
+import lib
+
+def helper():
+	lib.render()
+
+def unused():
+	helper()
+
+if __name__ == "__main__":
+	lib.parse()
+`,
+	})
+
+	parse := func(relPath string) ParsedFile {
+		filePath := filepath.Join(projectRoot, relPath)
+		contents, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+
+		py, err := ParsePython(filePath, contents)
+		require.NoError(t, err)
+		return py
+	}
+
+	cg := NewCallGraph()
+	cli, script := parse("cli.py"), parse("script.py")
+
+	require.NoError(t, cg.AddEntrypoint(cli, Entrypoint{Name: "cli", FilePath: cli.Module().FileName, Function: "main"}))
+	require.NoError(t, cg.AddEntrypoint(cli, Entrypoint{Name: "cli-run", FilePath: cli.Module().FileName, Function: "Cli.run"}))
+	require.NoError(t, cg.AddEntrypoint(script, Entrypoint{Name: "script.py", FilePath: script.Module().FileName}))
+
+	err := cg.AddEntrypoint(cli, Entrypoint{Name: "cli-stop", FilePath: cli.Module().FileName, Function: "stop"})
+	assert.ErrorContains(t, err, "could not find function stop")
+
+	reached := make(map[string][]string)
+	cg.Walk(func(entrypoint *Entrypoint, cgNode *CgNode, path []*CgNode) {
+		if cgNode.FuncName == nil || *cgNode.FuncName == ModuleInitializerName {
+			return
+		}
+
+		if filepath.Base(cgNode.File.Module().FileName) == "lib.py" {
+			reached[entrypoint.Name] = append(reached[entrypoint.Name], *cgNode.FuncName)
+		}
+	})
+
+	// A script only reaches what its top-level code calls, and
+	// not the functions that are called by unreachable functions.
+	assert.Equal(t, map[string][]string{
+		"cli":       {"parse"},
+		"cli-run":   {"fetch"},
+		"script.py": {"parse"},
+	}, reached)
+	assert.NotContains(t, reached["script.py"], "render")
+}
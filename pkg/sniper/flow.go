@@ -0,0 +1,442 @@
+package sniper
+
+import (
+	"math"
+	"slices"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// flowValue is a value that is tracked by the value flow analysis: a function, class or
+// module definition, or an instance of a class (represented by the call to its constructor).
+// It is also used for any definition that an expression resolves to (see `resolveExprs`).
+type flowValue struct {
+	file ParsedFile
+	node *sitter.Node
+	// dynamic is `true` for definitions that are looked up by name at runtime (see `EdgeDynamic`)
+	dynamic bool
+}
+
+// valueSet is a set of values that preserves the order in which values were added.
+type valueSet struct {
+	values []flowValue
+	seen   map[*sitter.Node]struct{}
+}
+
+// add adds a value to the set, and returns `true` if it wasn't in the set already.
+func (vs *valueSet) add(value flowValue) bool {
+	if _, exists := vs.seen[value.node]; exists {
+		return false
+	}
+
+	vs.seen[value.node] = struct{}{}
+	vs.values = append(vs.values, value)
+	return true
+}
+
+// callSite is a call expression, along with the file that it is in.
+type callSite struct {
+	file ParsedFile
+	call *sitter.Node
+}
+
+// valueFlow is a flow-insensitive, interprocedural analysis that finds the values passed to
+// function parameters. The values of the arguments at every known call site are propagated to
+// the parameters of the callee, until no parameter gets a new value. When a parameter gets a
+// new value, the call sites in its function are propagated again, since their arguments may
+// refer to it. Call sites are known when they're in the file that the call graph starts from,
+// or in a function that it traverses, since values passed in code that never runs can't reach
+// a parameter. Assignments are followed by resolving names lexically, and return values are
+// resolved on demand.
+// So, in this snippet:
+// ```py
+// def run(fn): fn()
+// job = worker
+// run(job)
+// ```
+// The parameter `fn` gets the value `def worker(): ...`.
+type valueFlow struct {
+	// indexedNodes are the files and functions whose call sites are known
+	indexedNodes map[*sitter.Node]struct{}
+	callSites    []callSite
+	// pending are the indexes of the call sites whose arguments need to be propagated
+	pending []int
+	// isPending is the set of indexes in `pending`
+	isPending map[int]struct{}
+	// paramValues maps the identifier of a parameter to the values passed to it
+	paramValues map[*sitter.Node]*valueSet
+	// inProgress maps the calls, functions and parameters whose values are being resolved
+	// right now to their depth: the number of resolutions that they're nested in, plus one
+	inProgress map[resolution]int
+	// frames are the IDs of the resolutions in progress, from the outermost one
+	frames    []int
+	nextFrame int
+	// cutAt is the depth of the innermost resolution in progress that the values which were
+	// left out to break cycles are missing from. The resolutions nested deeper than it may be
+	// incomplete (see `leaveOut`). It is `math.MaxInt` if none may be incomplete.
+	cutAt int
+	// resolved caches the values of the calls, functions and parameters that were resolved
+	resolved map[resolution]resolvedValues
+	solving  bool
+	// stale are the traversed functions whose parameters got new values after
+	// they were traversed, and whose calls must be resolved again.
+	stale []flowValue
+	// isStale is the set of function definitions in `stale`
+	isStale    map[*sitter.Node]struct{}
+	refreshing bool
+	// traversed are the functions that were traversed, in the order they were traversed in
+	traversed []flowValue
+	// changed is `true` if a parameter got new values since every traversed function was refreshed
+	changed bool
+}
+
+func newValueFlow() *valueFlow {
+	return &valueFlow{
+		indexedNodes: make(map[*sitter.Node]struct{}),
+		isPending:    make(map[int]struct{}),
+		paramValues:  make(map[*sitter.Node]*valueSet),
+		inProgress:   make(map[resolution]int),
+		cutAt:        math.MaxInt,
+		resolved:     make(map[resolution]resolvedValues),
+		isStale:      make(map[*sitter.Node]struct{}),
+	}
+}
+
+// indexCallSites adds the call sites in `node` (a module or a function definition)
+// to the value flow analysis. Calls in nested functions are left out, since they are
+// indexed when the call graph traverses those functions.
+func (cg *CallGraph) indexCallSites(file ParsedFile, node *sitter.Node) {
+	flow := cg.flow
+	if _, indexed := flow.indexedNodes[node]; indexed {
+		return
+	}
+
+	flow.indexedNodes[node] = struct{}{}
+
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			if file.IsFunctionDef(child) {
+				continue
+			}
+
+			if file.IsCallExpr(child) {
+				flow.callSites = append(flow.callSites, callSite{file: file, call: child})
+				flow.enqueue(len(flow.callSites) - 1)
+			}
+
+			visit(child)
+		}
+	}
+
+	visit(node)
+}
+
+// solveValueFlow propagates values to parameters until a fixed point is reached.
+func (cg *CallGraph) solveValueFlow() {
+	flow := cg.flow
+	if flow.solving {
+		// Values are being propagated already, so the values found so
+		// far are used. They are updated until they stop changing.
+		return
+	}
+
+	flow.solving = true
+	defer func() { flow.solving = false }()
+
+	// New call sites may be indexed, and call sites may be queued again, while propagating
+	for len(flow.pending) > 0 {
+		site := flow.pending[0]
+		flow.pending = flow.pending[1:]
+		delete(flow.isPending, site)
+
+		cg.propagateArguments(flow.callSites[site])
+	}
+}
+
+// enqueue queues the call site at index `site` to have its arguments propagated.
+func (flow *valueFlow) enqueue(site int) {
+	if _, queued := flow.isPending[site]; queued {
+		return
+	}
+
+	flow.isPending[site] = struct{}{}
+	flow.pending = append(flow.pending, site)
+}
+
+// enqueueCallSitesIn queues the call sites in a function (including the ones in nested functions).
+// When a parameter of the function gets a new value, only these arguments can refer to it by name.
+func (flow *valueFlow) enqueueCallSitesIn(file ParsedFile, fn *sitter.Node) {
+	for i, site := range flow.callSites {
+		if site.file.Module() == file.Module() &&
+			fn.StartByte() <= site.call.StartByte() && site.call.EndByte() <= fn.EndByte() {
+			flow.enqueue(i)
+		}
+	}
+}
+
+// propagateArguments adds the values of the arguments at a call site to the parameters of the callee.
+func (cg *CallGraph) propagateArguments(site callSite) {
+	for _, callee := range cg.resolveCallees(site.file, site.call) {
+		if callee.file.IsFunctionDef(callee.node) {
+			cg.propagateArgumentsTo(site, callee.file, callee.node)
+		}
+	}
+}
+
+// propagateArgumentsTo adds the values of the arguments at a call site to the parameters of one of its callees.
+func (cg *CallGraph) propagateArgumentsTo(site callSite, calleeFile ParsedFile, callee *sitter.Node) {
+	params := calleeFile.ParametersOf(callee)
+	position := 0
+	for _, arg := range site.file.ArgumentsOf(site.call) {
+		if arg.IsSplat {
+			// The positions of arguments after `*args` aren't known
+			position = -1
+			continue
+		}
+
+		var param *Parameter
+		if arg.Keyword != "" {
+			for i := range params {
+				if params[i].Name == arg.Keyword && !params[i].IsVariadic {
+					param = &params[i]
+					break
+				}
+			}
+		} else if position >= 0 {
+			if position < len(params) && !params[position].IsVariadic && !params[position].IsKeywordOnly {
+				param = &params[position]
+			}
+			position++
+		}
+
+		if param != nil {
+			cg.addParamValues(calleeFile, callee, *param, cg.valuesOf(site.file, arg.Value))
+		}
+	}
+}
+
+// addParamValues adds values to the set of values passed to a parameter of `fn`.
+func (cg *CallGraph) addParamValues(file ParsedFile, fn *sitter.Node, param Parameter, values []flowValue) {
+	flow := cg.flow
+	paramValues := flow.paramValues[param.Node]
+	if paramValues == nil {
+		paramValues = &valueSet{seen: make(map[*sitter.Node]struct{})}
+		flow.paramValues[param.Node] = paramValues
+	}
+
+	changed := false
+	for _, value := range values {
+		if paramValues.add(value) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	// Any value that was resolved may refer to the parameter,
+	// including the values of the resolutions in progress.
+	clear(flow.resolved)
+	flow.leaveOut(0)
+	flow.changed = true
+	flow.enqueueCallSitesIn(file, fn)
+
+	// The calls in a function that was traversed already were resolved without these values
+	if _, traversed := cg.CallGraphOfNode[fn]; traversed {
+		flow.markStale(flowValue{file: file, node: fn})
+	}
+}
+
+// markStale adds a traversed function to the functions that must be traversed again.
+func (flow *valueFlow) markStale(fn flowValue) {
+	if _, isStale := flow.isStale[fn.node]; !isStale {
+		flow.isStale[fn.node] = struct{}{}
+		flow.stale = append(flow.stale, fn)
+	}
+}
+
+// valuesOf returns the values that an expression may evaluate to.
+func (cg *CallGraph) valuesOf(file ParsedFile, expr *sitter.Node) []flowValue {
+	var values []flowValue
+	for _, def := range cg.resolveExprs(file, expr) {
+		if cg.isFlowValue(def.file, def.node) {
+			values = append(values, def)
+		}
+	}
+
+	return values
+}
+
+// isFlowValue returns `true` if a node is a value tracked by the value flow analysis.
+func (cg *CallGraph) isFlowValue(file ParsedFile, node *sitter.Node) bool {
+	if file.IsFunctionDef(node) || file.IsClassDef(node) || node == file.Module().Ast {
+		return true
+	}
+
+	return len(cg.resolveClassesOfInstance(file, node)) > 0
+}
+
+// resolveParameter returns the values passed to a parameter, or the values of its default value.
+func (cg *CallGraph) resolveParameter(file ParsedFile, paramNode *sitter.Node) []flowValue {
+	cg.solveValueFlow()
+
+	if paramValues := cg.flow.paramValues[paramNode]; paramValues != nil && len(paramValues.values) > 0 {
+		return paramValues.values
+	}
+
+	// A default value can refer to the parameter itself (e.g: `def f(x=x)`)
+	key := resolution{node: paramNode}
+	values, resolving := cg.flow.startResolving(key)
+	if !resolving {
+		return values
+	}
+
+	fn := paramNode.Parent()
+	for fn != nil && !file.IsFunctionDef(fn) {
+		fn = fn.Parent()
+	}
+
+	if fn != nil {
+		for _, param := range file.ParametersOf(fn) {
+			if param.Node == paramNode && param.Default != nil {
+				values = cg.valuesOf(file, param.Default)
+				break
+			}
+		}
+	}
+
+	cg.flow.finishResolving(key, values)
+	return values
+}
+
+// resolveReturnValues returns the values returned by the functions that `callExpr` may call.
+func (cg *CallGraph) resolveReturnValues(file ParsedFile, callExpr *sitter.Node) []flowValue {
+	// Calls can be defined in terms of each other (e.g: `a = b.f()` and `b = a.g()`),
+	// and recursive functions return their own return values.
+	key := resolution{node: callExpr}
+	values, resolving := cg.flow.startResolving(key)
+	if !resolving {
+		return values
+	}
+
+	if callee := file.GetCallee(callExpr); callee != nil {
+		for _, fn := range cg.resolveExprs(file, callee) {
+			// Calling a class creates an instance, which is represented by the call itself.
+			if fn.file.IsFunctionDef(fn.node) {
+				values = append(values, cg.returnValuesOf(fn.file, fn.node)...)
+			}
+		}
+	}
+
+	cg.flow.finishResolving(key, values)
+	return values
+}
+
+// returnValuesOf returns the values that a function definition may return, including an
+// instance of its annotated return type (which may be annotated in the module's stub).
+func (cg *CallGraph) returnValuesOf(file ParsedFile, fn *sitter.Node) []flowValue {
+	key := resolution{node: fn}
+	values, resolving := cg.flow.startResolving(key)
+	if !resolving {
+		return values
+	}
+
+	for _, expr := range file.ReturnedExprsOf(fn) {
+		values = append(values, cg.valuesOf(file, expr)...)
+	}
+
+	if annotation := file.TypeAnnotationOf(fn); annotation != nil {
+		values = append(values, cg.annotatedInstance(file, annotation)...)
+	} else {
+		// The stub of a module declares the return types that its source doesn't
+		for _, stubFn := range cg.stubDefinitionsOf(file, fn) {
+			if stubFn.file.IsFunctionDef(stubFn.node) {
+				values = append(values, cg.annotatedInstance(stubFn.file, stubFn.file.TypeAnnotationOf(stubFn.node))...)
+			}
+		}
+	}
+
+	cg.flow.finishResolving(key, values)
+	return values
+}
+
+// resolution is a node whose values are being resolved, or the classes that it is an instance of.
+type resolution struct {
+	node    *sitter.Node
+	classes bool
+}
+
+// resolvedValues are the values that a call, function or parameter was resolved to.
+type resolvedValues struct {
+	values []flowValue
+	// frame is the ID of the resolution that the values were resolved in, if they may be
+	// incomplete: values that were left out to break a cycle (see `leaveOut`) are left out of
+	// them too, as long as that resolution is in progress. It is 0 if they are complete.
+	frame int
+	// depth is the depth of that resolution
+	depth int
+}
+
+// startResolving starts resolving the values of a call, function or parameter. It returns
+// `false` along with the values that it was resolved to if it was resolved already, or along
+// with no values if it is being resolved right now (which leaves its values out to break the cycle).
+func (flow *valueFlow) startResolving(key resolution) ([]flowValue, bool) {
+	if resolved, exists := flow.resolved[key]; exists {
+		if resolved.frame == 0 {
+			return resolved.values, false
+		}
+
+		// Incomplete values are only reused while the values that
+		// they're missing would still be left out if resolved again.
+		if resolved.depth <= len(flow.frames) && flow.frames[resolved.depth-1] == resolved.frame {
+			flow.leaveOut(resolved.depth - 1)
+			return resolved.values, false
+		}
+
+		delete(flow.resolved, key)
+	}
+
+	if depth, inProgress := flow.inProgress[key]; inProgress {
+		flow.leaveOut(depth)
+		return nil, false
+	}
+
+	flow.nextFrame++
+	flow.frames = append(flow.frames, flow.nextFrame)
+	flow.inProgress[key] = len(flow.frames)
+	return nil, true
+}
+
+// depth returns the number of value flow resolutions in progress.
+func (flow *valueFlow) depth() int {
+	return len(flow.frames)
+}
+
+// leaveOut marks the resolutions nested deeper than `depth` (see `depth`) as incomplete,
+// when the values of a lookup that started at that depth were left out to break a cycle.
+func (flow *valueFlow) leaveOut(depth int) {
+	flow.cutAt = min(flow.cutAt, depth)
+}
+
+// finishResolving caches the values that a node was resolved to.
+func (flow *valueFlow) finishResolving(key resolution, values []flowValue) {
+	depth := flow.inProgress[key]
+	resolved := resolvedValues{values: slices.Clip(values)}
+	if flow.cutAt < depth {
+		resolved.depth = flow.cutAt + 1
+		resolved.frame = flow.frames[resolved.depth-1]
+	}
+
+	flow.resolved[key] = resolved
+	delete(flow.inProgress, key)
+	flow.frames = flow.frames[:depth-1]
+
+	// The resolution that this one is nested in is only incomplete
+	// if the values were left out of a resolution that encloses it.
+	if flow.cutAt >= depth-1 {
+		flow.cutAt = math.MaxInt
+	}
+}
@@ -12,7 +12,7 @@ type CallExprWalker struct {
 }
 
 func (c *CallExprWalker) OnEnterNode(node *sitter.Node) bool {
-	if node.Type() == "call" {
+	if c.file.IsCallExpr(node) || c.file.IsDecoratorCall(node) {
 		c.callGraph.FindCallGraph(c.file, node)
 	}
 
@@ -29,6 +29,9 @@ func CallGraphFromFile(file ParsedFile, moduleCache map[string]ParsedFile) *Call
 	cgWalker := &CallExprWalker{callGraph: cg, file: file}
 	util.WalkTree(file.Module().Ast, cgWalker)
 
+	// The file is the program's entrypoint, so its top-level code runs too.
+	cg.ModuleInitializer(file)
+
 	return cg
 }
 
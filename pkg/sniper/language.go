@@ -9,14 +9,94 @@ type Module struct {
 	Ast *sitter.Node
 	// ProjectRoot is the root directory of the project to which
 	// this module belongs
-	ProjectRoot      *string
-	FileName         string
-	Source           []byte
-	GlobalScope      *Scope
-	ScopeOfNode      ScopeOfNode
-	TsLanguage       *sitter.Language
-	FilePathOfImport map[*sitter.Node]string
-	Language         Language
+	ProjectRoot *string
+	FileName    string
+	Source      []byte
+	GlobalScope *Scope
+	ScopeOfNode ScopeOfNode
+	TsLanguage  *sitter.Language
+	// ImportTargets caches the resolved target of every imported name
+	ImportTargets map[ImportedName]*ImportTarget
+	Language      Language
+}
+
+// ImportedName identifies a single name bound by an import statement.
+// e.g: `b` in `from a import b, c`
+type ImportedName struct {
+	// Node is the import statement
+	Node *sitter.Node
+	// Name is the local name that the statement binds
+	Name string
+}
+
+// ImportTarget is the definition that an imported name refers to.
+type ImportTarget struct {
+	// FilePath is the absolute path of the imported module.
+	// It is empty for namespace packages, which have no file of their own.
+	FilePath string
+	// Symbol is the name of the imported definition inside the module.
+	// It is empty when the import binds the module itself (e.g: `import os`).
+	Symbol string
+	// SubmodulePath is the file of a submodule named `Symbol`, if one exists.
+	// Python looks `Symbol` up in the module first, and falls back to
+	// importing the submodule when the module doesn't define it.
+	SubmodulePath string
+}
+
+// Argument is a single argument passed to a call expression.
+type Argument struct {
+	// Value is the expression that is passed
+	Value *sitter.Node
+	// Keyword is the name of the parameter for keyword arguments (e.g: `target` in `Thread(target=f)`),
+	// and empty for positional arguments.
+	Keyword string
+	// IsSplat is `true` for arguments that are unpacked into many arguments (e.g: `*args` in python)
+	IsSplat bool
+}
+
+// Parameter is a single parameter of a function definition.
+type Parameter struct {
+	Name string
+	// Node is the identifier that declares the parameter
+	Node *sitter.Node
+	// Default is the default value of the parameter, if it has one
+	Default *sitter.Node
+	// IsVariadic is `true` for parameters that collect any
+	// number of arguments (e.g: `*args` and `**kwargs` in python)
+	IsVariadic bool
+	// IsKeywordOnly is `true` for parameters that can't be passed positionally
+	IsKeywordOnly bool
+}
+
+// ImplicitCall is a method that a syntactic form calls without a call expression
+// (e.g: `__enter__` in `with a:` in python).
+type ImplicitCall struct {
+	// Receiver is the expression for the object whose method is called
+	Receiver *sitter.Node
+	// Method is the name of the method that is called
+	Method string
+	// OnResult is the name of a method that is then called on the value that
+	// `Method` returns, if any (e.g: `__next__` on the iterator returned by `__iter__`).
+	OnResult string
+	// PropertyOnly is `true` when the method is only called if it
+	// is a property getter (e.g: `b` when `a.b` is read in python).
+	PropertyOnly bool
+}
+
+// DynamicLookup is a call that imports a module, or reads an attribute, by a name that is only
+// known at runtime (e.g: `importlib.import_module(name)` and `getattr(obj, name)` in python).
+type DynamicLookup struct {
+	// Object is the expression for the object whose attribute is read, and nil for imports
+	Object *sitter.Node
+	// Name is the expression for the name of the module or the attribute
+	Name *sitter.Node
+	// Package is the expression for the package that a relative module name is relative to, if any
+	Package *sitter.Node
+	// Default is the expression for the value of an attribute that doesn't exist, if any
+	Default *sitter.Node
+	// TopLevel is `true` when an import evaluates to the top-level package of the
+	// module, rather than the module itself (e.g: `__import__("a.b")` in python).
+	TopLevel bool
 }
 
 type Language int
@@ -36,10 +116,40 @@ type ParsedFile interface {
 	// GetDecls reutrns a list of all declarations made in *sitter.Node
 	// (does not traverse blocks and such, the argument should be an assignment node)
 	GetDecls(*sitter.Node) []Decl
+	// GlobalNamesOf returns the names that a statement declares to be
+	// bound in the global scope (e.g: `a` in `global a` in python).
+	GlobalNamesOf(*sitter.Node) []string
+	// NonlocalNamesOf returns the names that a statement declares to be bound in
+	// the nearest enclosing function scope (e.g: `a` in `nonlocal a` in python).
+	NonlocalNamesOf(*sitter.Node) []string
+	// IsEvaluatedInEnclosingScope returns `true` if a node is a part of a block node that is evaluated
+	// in the scope enclosing the block, rather than in the block's own scope (e.g: the base classes of
+	// a class, or the default values of a function's parameters in python).
+	IsEvaluatedInEnclosingScope(*sitter.Node) bool
+	// IsComprehension returns `true` if the node is a comprehension (in python), whose scope only
+	// binds its own loop variables.
+	IsComprehension(*sitter.Node) bool
+	// IsAssignmentExpr returns `true` if the node is an assignment expression (e.g: `x := f()` in
+	// python), which binds its name in the nearest scope that isn't a comprehension.
+	IsAssignmentExpr(*sitter.Node) bool
 	// IsCallExpr returns `true` if the node is a call expression
 	IsCallExpr(*sitter.Node) bool
+	// IsDecoratorCall returns `true` if the node is a decorator that calls its expression with the
+	// decorated definition, and isn't a call expression itself (e.g: `@cache` in python).
+	// The callee of such a decorator is found with `GetCallee`, just like for call expressions.
+	IsDecoratorCall(*sitter.Node) bool
 	// IsFunctionDef returns `true` if the node is a function definition/expression
 	IsFunctionDef(*sitter.Node) bool
+	// IsClassDef returns `true` if the node is a class definition
+	IsClassDef(*sitter.Node) bool
+	// ReceiverClassOf returns the class definition that a node is an instance of, if the node
+	// is the receiver parameter of a method (e.g: `self` in python). Returns nil otherwise.
+	ReceiverClassOf(*sitter.Node) *sitter.Node
+	// SuperclassesOf returns the expressions that name the base classes of a class definition.
+	SuperclassesOf(*sitter.Node) []*sitter.Node
+	// SuperCallOwner returns the expression for the class that a `super()` call (in python)
+	// looks attributes up after. For any other node, it returns nil.
+	SuperCallOwner(*sitter.Node) *sitter.Node
 
 	// IsDottedExpr returns `true` if the argument is a member expression like "foo.bar".
 	IsDottedExpr(*sitter.Node) bool
@@ -58,11 +168,39 @@ type ParsedFile interface {
 	FunctionDefFromNode(*sitter.Node) *sitter.Node
 
 	// GetCallee returns the callee for a call expression
-	// The argument *must* be a call expression.
+	// The argument *must* be a call expression or a decorator call.
 	GetCallee(*sitter.Node) *sitter.Node
 
 	// GetCalleeName returns the name of the callee in a function call node
 	GetCalleeName(*sitter.Node) *string
+	// ArgumentsOf returns the arguments passed to a call expression, in order.
+	ArgumentsOf(*sitter.Node) []Argument
+	// ParametersOf returns the parameters of a function definition that arguments are passed to.
+	ParametersOf(*sitter.Node) []Parameter
+	// ReturnedExprsOf returns the expressions that a function definition returns.
+	ReturnedExprsOf(*sitter.Node) []*sitter.Node
+	// ElementsOf returns the elements of a container literal (like a list),
+	// or the values of a dictionary literal. Returns nil for any other node.
+	ElementsOf(*sitter.Node) []*sitter.Node
+	// LoopIterableOf returns the expression that a loop iterates over, if `idNode`
+	// refers to the variable of an enclosing loop (e.g: `xs` for `x` in `for x in xs: x()`).
+	LoopIterableOf(idNode *sitter.Node) *sitter.Node
+	// ImplicitCallsOf returns the methods that a node calls on its operands without a call expression
+	// (like the `__add__` in `a + b` in python). Returns nil for nodes that don't call any methods.
+	ImplicitCallsOf(*sitter.Node) []ImplicitCall
+	// IsPropertyGetter returns `true` if a method definition is called when it is read as
+	// an attribute, rather than when it is called (e.g: `@property` methods in python).
+	IsPropertyGetter(*sitter.Node) bool
+	// TypeAnnotationOf returns the type annotation of a parameter (given its identifier), or the
+	// return type annotation of a function definition. Returns nil when there is no annotation.
+	TypeAnnotationOf(*sitter.Node) *sitter.Node
+	// AnnotatedTypesOf returns the dotted names of the classes that a type annotation says a value is
+	// an instance of (e.g: `httpx.Client`). Returns nil if the node isn't a type annotation.
+	AnnotatedTypesOf(annotation *sitter.Node) []string
+	// InstanceAttributesOf returns the attributes that the methods of a class assign on their
+	// receiver (e.g: `self.session = Session()` in python), mapped to the values that they
+	// are bound to. This is the instance's attribute table.
+	InstanceAttributesOf(class *sitter.Node) map[string][]*sitter.Node
 
 	// BodyOfFunction returns the body (e.g list of stmts) of a function node.
 	BodyOfFunction(*sitter.Node) *sitter.Node
@@ -71,15 +209,56 @@ type ParsedFile interface {
 	// IsImport returns `true` if the node is a import statement (or expression, in some languages)
 	IsImport(*sitter.Node) bool
 	IsModuleImport(*sitter.Node) bool
-	// FilePathOfImport resolves an import statement node to an absolute file path
-	// Will return an empty string when resolution fails
-	// TODO: single import can have multiple files that it imports. maybe this should
-	// return a map of imported node/node-name to the filepath instead.
-	FilePathOfImport(*sitter.Node) *string
-	// ResolveExportedSymbol resolves an exported symbol to its definition node
-	ResolveExportedSymbol(string) *sitter.Node
+	// ImportTargetOf resolves a name bound by an import statement to the module that
+	// it is imported from, and the symbol in that module.
+	// `name` is the local name of the binding (e.g: `c` in `from a import b as c`).
+	// Returns nil when resolution fails.
+	ImportTargetOf(importNode *sitter.Node, name string) *ImportTarget
+	// DynamicLookupOf returns the module or attribute that a call looks up by name.
+	// Returns nil for calls that don't look anything up, and for any other node.
+	DynamicLookupOf(*sitter.Node) *DynamicLookup
+	// StringValueOf returns the value of a constant string expression (like `"a"`), or nil
+	// if the node isn't one. This includes names whose values are known to every module
+	// (like `__name__` in python).
+	StringValueOf(*sitter.Node) *string
+	// FilePathOfModule returns the file of a module, given its absolute dotted
+	// name (e.g: `requests.sessions`). Returns nil when there is no such module.
+	FilePathOfModule(name string) *string
+	// ModulesRunBy returns the files of the modules whose top-level code runs
+	// when an import statement is executed, in the order that they run.
+	ModulesRunBy(importNode *sitter.Node) []string
+	// FilePathOfSubmodule returns the file of a submodule named `name`, if this file is a
+	// package (e.g: `a/b.py` for `b` in `a/__init__.py`). Returns nil otherwise.
+	FilePathOfSubmodule(name string) *string
+	// ParseImportedFile parses a file that is imported by this file.
+	// The imported file resolves its own imports the same way as this file
+	// (e.g: with the same sys.path in python).
+	ParseImportedFile(filePath string) (ParsedFile, error)
+	// ResolveExportedSymbol resolves an exported symbol to its definition nodes.
+	// A symbol can be defined more than once (e.g: conditionally), so every definition is returned.
+	ResolveExportedSymbol(string) []*sitter.Node
+	// ExportsToWildcard returns `true` if a wildcard import of this
+	// file (e.g: `from a import *` in python) binds `name`.
+	ExportsToWildcard(name string) bool
+
+	// StubFilePath returns the stub file that describes this file's module, if there is one
+	// (e.g: `a.pyi` for `a.py` in python). Stubs only declare types, so they're used to learn
+	// the class hierarchies and return types that the source doesn't spell out.
+	StubFilePath() *string
+	// ImplementationFilePath returns the source file of the module that a stub file describes.
+	// Returns nil for files that aren't stubs, and for modules that have no source.
+	ImplementationFilePath() *string
+
+	// ModuleName returns the dotted import path of this file (e.g: `requests.sessions`)
+	ModuleName() *string
+	// QualifiedNameOf returns the name of a function or class definition, qualified
+	// by the names of its enclosing classes and functions (e.g: `Session.request`)
+	QualifiedNameOf(*sitter.Node) *string
 
 	// Returns the name of the package that this file belongs to.
 	// Usually, this is the name of a dependency (e.g: `requests` in python)
 	PackageName() *string
+	// PackageVersion returns the installed version of the package that this file belongs to.
+	// Returns nil for first-party files, or when the version is unknown.
+	PackageVersion() *string
 }
@@ -0,0 +1,225 @@
+package sniper
+
+import (
+	"slices"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// classRef is a class definition, along with the file that it is defined in.
+type classRef struct {
+	File  ParsedFile
+	Class *sitter.Node
+	// Via is the base class expression that a base class in an MRO was resolved from, when it
+	// may be one of several classes (e.g: when it is imported in both branches of a `try`).
+	// The classes with the same `Via` are alternatives of each other (see `lookupInMro`).
+	Via *sitter.Node
+}
+
+// mroOf returns the method resolution order of a class: the class itself, followed by
+// its base classes in the order that attributes are looked up in them.
+// The order is computed with C3 linearization, the same algorithm that python uses.
+// Base classes that can't be resolved (e.g: builtins like `object`) are left out.
+// Bases that are only declared in the module's stub are added after the ones in its source.
+func (cg *CallGraph) mroOf(file ParsedFile, class *sitter.Node) []classRef {
+	if mro, cached := cg.mroCache[class]; cached {
+		return mro
+	}
+
+	self := classRef{File: file, Class: class}
+
+	// A class that (indirectly) inherits from itself is invalid, but the
+	// call graph must not loop forever on one. Its bases are ignored instead.
+	cg.mroCache[class] = []classRef{self}
+
+	var bases []classRef
+	addBases := func(baseExpr *sitter.Node, classes []flowValue) {
+		var via *sitter.Node
+		if len(classes) > 1 {
+			via = baseExpr
+		}
+
+		for _, base := range classes {
+			if !slices.ContainsFunc(bases, func(ref classRef) bool { return ref.Class == base.node }) {
+				bases = append(bases, classRef{File: base.file, Class: base.node, Via: via})
+			}
+		}
+	}
+
+	for _, baseExpr := range file.SuperclassesOf(class) {
+		var classes []flowValue
+		for _, base := range cg.resolveExprs(file, baseExpr) {
+			if base.file.IsClassDef(base.node) {
+				classes = append(classes, base)
+			}
+		}
+
+		addBases(baseExpr, classes)
+	}
+
+	// The stub of a module may declare bases that can't be resolved from
+	// its source (like the bases of classes in C extensions).
+	for _, stubClass := range cg.stubDefinitionsOf(file, class) {
+		if !stubClass.file.IsClassDef(stubClass.node) {
+			continue
+		}
+
+		for _, baseExpr := range stubClass.file.SuperclassesOf(stubClass.node) {
+			var classes []flowValue
+			for _, base := range cg.resolveExprs(stubClass.file, baseExpr) {
+				if base.file.IsClassDef(base.node) {
+					classes = append(classes, cg.implementationsOf(base.file, base.node)...)
+				}
+			}
+
+			addBases(baseExpr, classes)
+		}
+	}
+
+	var seqs [][]classRef
+	for _, base := range bases {
+		seqs = append(seqs, cg.mroOf(base.File, base.Class))
+	}
+	seqs = append(seqs, bases)
+
+	mro := append([]classRef{self}, c3Merge(seqs)...)
+
+	// The bases come first in their own linearizations, which don't know how they were resolved
+	for i := range mro {
+		if j := slices.IndexFunc(bases, func(ref classRef) bool { return ref.Class == mro[i].Class }); j >= 0 {
+			mro[i].Via = bases[j].Via
+		}
+	}
+
+	cg.mroCache[class] = mro
+	return mro
+}
+
+// c3Merge merges the linearizations of a class's bases (and the list of bases itself)
+// into a single list, where every class comes before its bases, and the order of bases
+// in every input list is preserved.
+func c3Merge(seqs [][]classRef) []classRef {
+	inTail := func(class *sitter.Node) bool {
+		for _, seq := range seqs {
+			for _, ref := range seq[1:] {
+				if ref.Class == class {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var merged []classRef
+	for {
+		var nonEmpty [][]classRef
+		for _, seq := range seqs {
+			if len(seq) > 0 {
+				nonEmpty = append(nonEmpty, seq)
+			}
+		}
+
+		seqs = nonEmpty
+		if len(seqs) == 0 {
+			return merged
+		}
+
+		// The next class is the first head that isn't a base of a class yet to be merged.
+		// When there is no such head, the hierarchy is inconsistent (python raises a TypeError
+		// for it), and the first head is taken anyways.
+		next := seqs[0][0]
+		for _, seq := range seqs {
+			if !inTail(seq[0].Class) {
+				next = seq[0]
+				break
+			}
+		}
+
+		merged = append(merged, next)
+		for i, seq := range seqs {
+			var rest []classRef
+			for _, ref := range seq {
+				if ref.Class != next.Class {
+					rest = append(rest, ref)
+				}
+			}
+			seqs[i] = rest
+		}
+	}
+}
+
+// lookupInMro finds the definitions of an attribute or method of a class,
+// by looking it up in the classes of its MRO (starting at index `start`).
+// Every definition in the first class that defines the name is returned
+// (e.g: a method that is defined in both branches of an `if`), and so are the
+// definitions that the alternatives of the classes before it (see `classRef.Via`) have.
+func (cg *CallGraph) lookupInMro(file ParsedFile, class *sitter.Node, name string, start int) []flowValue {
+	mro := cg.mroOf(file, class)
+	for i := start; i < len(mro); i++ {
+		defs := cg.definitionsInClass(mro[i], name)
+		if len(defs) == 0 {
+			continue
+		}
+
+		for _, alternative := range mro[i+1:] {
+			isAlternative := func(ref classRef) bool { return ref.Via != nil && ref.Via == alternative.Via }
+			if !slices.ContainsFunc(mro[start:i+1], isAlternative) {
+				continue
+			}
+
+			for _, def := range cg.lookupInMro(alternative.File, alternative.Class, name, 0) {
+				if !slices.Contains(defs, def) {
+					defs = append(defs, def)
+				}
+			}
+		}
+
+		return defs
+	}
+
+	return nil
+}
+
+// definitionsInClass returns the definitions of an attribute or method in the body of a class.
+func (cg *CallGraph) definitionsInClass(ref classRef, name string) []flowValue {
+	scope := ref.File.Module().ScopeOfNode[ref.Class]
+	if scope == nil {
+		return nil
+	}
+
+	var defs []flowValue
+	for _, decl := range scope.Definitions[name] {
+		if ref.File.IsImport(decl) {
+			defs = append(defs, cg.resolveImport(ref.File, decl, name)...)
+		} else {
+			defs = append(defs, flowValue{file: ref.File, node: decl})
+		}
+	}
+
+	return defs
+}
+
+// instanceAttributeOf returns the values that the methods of a class, and of its base classes,
+// assign to an attribute of their receiver (see `ParsedFile.InstanceAttributesOf`).
+func (cg *CallGraph) instanceAttributeOf(file ParsedFile, class *sitter.Node, name string) []flowValue {
+	var defs []flowValue
+	for _, ref := range cg.mroOf(file, class) {
+		for _, node := range ref.File.InstanceAttributesOf(ref.Class)[name] {
+			defs = append(defs, flowValue{file: ref.File, node: node})
+		}
+	}
+
+	return defs
+}
+
+// constructorsOf returns the constructors of a class, which may be inherited from a base class.
+func (cg *CallGraph) constructorsOf(file ParsedFile, class *sitter.Node) []flowValue {
+	var ctors []flowValue
+	for _, ctor := range cg.lookupInMro(file, class, "__init__", 0) {
+		if ctor.file.IsFunctionDef(ctor.node) {
+			ctors = append(ctors, ctor)
+		}
+	}
+
+	return ctors
+}
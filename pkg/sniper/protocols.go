@@ -0,0 +1,70 @@
+package sniper
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// findImplicitCalls returns the call graph nodes of the methods that a node calls without
+// a call expression (see `ParsedFile.ImplicitCallsOf`), like the `__enter__` and `__exit__`
+// methods of a context manager. A method is only found when the class of its receiver is known.
+// Reading an attribute that a module doesn't define calls its `__getattr__` function (PEP 562),
+// which is reached by an `EdgeDynamic`.
+func (cg *CallGraph) findImplicitCalls(file ParsedFile, node *sitter.Node) []callTarget {
+	var methods []callTarget
+	for _, call := range file.ImplicitCallsOf(node) {
+		for _, instance := range cg.resolveExprs(file, call.Receiver) {
+			methods = append(methods, cg.findImplicitCall(instance, call)...)
+		}
+	}
+
+	return methods
+}
+
+// findImplicitCall returns the call graph nodes of the methods that an implicit call calls on an instance.
+func (cg *CallGraph) findImplicitCall(instance flowValue, call ImplicitCall) []callTarget {
+	if call.PropertyOnly && instance.node == instance.file.Module().Ast {
+		var getattrs []callTarget
+		for _, getattr := range cg.moduleGetattrsFor(instance.file, call.Method) {
+			getattrs = append(getattrs, callTarget{cgNode: cg.traverseFunction(instance.file, getattr), kind: EdgeDynamic})
+		}
+
+		return getattrs
+	}
+
+	var methods []callTarget
+	for _, method := range cg.methodsOfInstance(instance.file, instance.node, call.Method) {
+		if call.PropertyOnly && !method.file.IsPropertyGetter(method.node) {
+			continue
+		}
+
+		methods = append(methods, callTarget{cgNode: cg.traverseFunction(method.file, method.node), kind: EdgeCall})
+		if call.OnResult == "" {
+			continue
+		}
+
+		// The method is called on every value that was returned, like the
+		// iterators that `__iter__` returns (which are often `self`).
+		for _, result := range cg.returnValuesOf(method.file, method.node) {
+			for _, resultMethod := range cg.methodsOfInstance(result.file, result.node, call.OnResult) {
+				methods = append(methods, callTarget{cgNode: cg.traverseFunction(resultMethod.file, resultMethod.node), kind: EdgeCall})
+			}
+		}
+	}
+
+	return methods
+}
+
+// methodsOfInstance looks a method up in the classes of an instance (see `resolveClassesOfInstance`).
+// Returns nil when the classes are unknown, or when they have no method with that name.
+func (cg *CallGraph) methodsOfInstance(file ParsedFile, instance *sitter.Node, name string) []flowValue {
+	var methods []flowValue
+	for _, class := range cg.resolveClassesOfInstance(file, instance) {
+		for _, method := range cg.lookupInMro(class.File, class.Class, name, 0) {
+			if method.file.IsFunctionDef(method.node) {
+				methods = append(methods, method)
+			}
+		}
+	}
+
+	return methods
+}
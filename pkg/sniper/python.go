@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	sitter "github.com/smacker/go-tree-sitter"
@@ -13,8 +14,13 @@ import (
 )
 
 type Python struct {
-	module           *Module
-	SitePackagesPath string
+	module *Module
+	// SysPath is the list of directories that imports are resolved from.
+	SysPath *SysPath
+	// annotatedTypes caches the type names of every type annotation (see `AnnotatedTypesOf`)
+	annotatedTypes map[*sitter.Node][]string
+	// instanceAttributes caches the instance attributes of every class (see `InstanceAttributesOf`)
+	instanceAttributes map[*sitter.Node]map[string][]*sitter.Node
 }
 
 func (py *Python) Module() *Module {
@@ -54,18 +60,40 @@ func findProjectRoot(filePath string) (*string, error) {
 	return nil, fmt.Errorf("could not find a parent directory with setup.py")
 }
 
+// ParsePython parses a python file. Imports are resolved from a search path
+// that is auto-detected from the file's project root.
 func ParsePython(fileName string, source []byte) (*Python, error) {
+	return ParsePythonWithEnv(fileName, source, PythonEnv{})
+}
+
+// ParsePythonWithEnv parses a python file, and resolves its imports
+// from a search path built with `env`.
+func ParsePythonWithEnv(fileName string, source []byte, env PythonEnv) (*Python, error) {
 	projectRoot, _ := findProjectRoot(fileName)
 
+	searchRoot := filepath.Dir(fileName)
+	if sitePackagesDir := sitePackagesDirOf(fileName); sitePackagesDir != nil {
+		// Installed packages import each other relative to site-packages.
+		searchRoot = *sitePackagesDir
+	} else if projectRoot != nil {
+		searchRoot = *projectRoot
+	}
+
+	return parsePython(fileName, source, projectRoot, NewSysPath(searchRoot, env))
+}
+
+func parsePython(fileName string, source []byte, projectRoot *string, sysPath *SysPath) (*Python, error) {
 	python := &Python{module: &Module{
-		FileName:         fileName,
-		Source:           source,
-		Language:         LangPy,
-		ProjectRoot:      projectRoot,
-		TsLanguage:       treeSitterPy.GetLanguage(),
-		FilePathOfImport: make(map[*sitter.Node]string),
+		FileName:      fileName,
+		Source:        source,
+		Language:      LangPy,
+		ProjectRoot:   projectRoot,
+		TsLanguage:    treeSitterPy.GetLanguage(),
+		ImportTargets: make(map[ImportedName]*ImportTarget),
 	},
-		SitePackagesPath: "/Users/srijan-paul/work/reachable/test-projects/pyproject/venv/lib/python3.12/site-packages",
+		SysPath:            sysPath,
+		annotatedTypes:     make(map[*sitter.Node][]string),
+		instanceAttributes: make(map[*sitter.Node]map[string][]*sitter.Node),
 	}
 
 	ast, err := sitter.ParseCtx(
@@ -91,34 +119,70 @@ func (py *Python) GetDecls(node *sitter.Node) []Decl {
 			lhs := node.ChildByFieldName("left")
 			rhs := node.ChildByFieldName("right")
 
-			if lhs == nil || rhs == nil {
+			annotation := node.ChildByFieldName("type")
+			if lhs == nil || (rhs == nil && annotation == nil) {
 				return nil
 			}
 
-			if lhs.Type() == "identifier" {
-				return []Decl{{lhs.Content(py.module.Source), rhs}}
-			} else if lhs.Type() == "pattern_list" && rhs.Type() == "expression_list" {
-				decls := []Decl{}
+			var decls []Decl
+			if rhs != nil {
+				// In a chained assignment like `a = b = f`, every target is bound to `f`.
+				for rhs.Type() == "assignment" && rhs.ChildByFieldName("right") != nil {
+					rhs = rhs.ChildByFieldName("right")
+				}
+
+				decls = py.bindPattern(lhs, rhs)
+			}
 
-				nLeftChildren := int(lhs.NamedChildCount())
-				nRightChildren := int(rhs.NamedChildCount())
+			// An annotated name (e.g: `x: Client`) is also bound to its annotation, which stands
+			// for an instance of the annotated type when the assigned value can't be resolved.
+			if annotation != nil && lhs.Type() == "identifier" {
+				decls = append(decls, Decl{lhs.Content(py.module.Source), annotation})
+			}
 
-				for i := 0; i < min(nLeftChildren, nRightChildren); i++ {
-					if lhs.NamedChild(i).Type() == "identifier" {
-						decls = append(decls, Decl{
-							lhs.NamedChild(i).Content(py.module.Source),
-							rhs.NamedChild(i),
-						})
-					}
-					// TODO(@Srijan/Tushar): nested patterns and all that
-				}
+			return decls
+		}
 
-				return decls
-			} else {
-				// panic(fmt.Sprintf("lhs type: %s, rhs: %s", lhs.Type(), rhs.Type()))
-				return nil
+	case "augmented_assignment":
+		{
+			// `x += y` rebinds `x` to a value that isn't known.
+			return py.bindPattern(node.ChildByFieldName("left"), nil)
+		}
+
+	case "named_expression":
+		{
+			// `(x := f())` binds `x` to `f()`
+			return py.bindPattern(node.ChildByFieldName("name"), node.ChildByFieldName("value"))
+		}
+
+	case "for_statement":
+		{
+			// Loop variables are bound to their own identifiers, since their values
+			// are the elements of the iterable and not the iterable itself.
+			return py.bindPattern(node.ChildByFieldName("left"), nil)
+		}
+
+	case "with_item":
+		{
+			// `with a as b` binds `b` to the value returned by `a.__enter__()`,
+			// which is usually the context manager itself.
+			manager, target := py.asPatternOf(node.ChildByFieldName("value"))
+			return py.bindPattern(target, manager)
+		}
+
+	case "except_clause":
+		{
+			// `except E as e` binds `e` to an instance of `E`
+			for i := 0; i < int(node.NamedChildCount()); i++ {
+				if _, target := py.asPatternOf(node.NamedChild(i)); target != nil {
+					return py.bindPattern(target, nil)
+				}
 			}
-			// TODO@(Srijan/Tushar) – Handle other assignment patterns
+		}
+
+	case "case_clause":
+		{
+			return py.bindCasePatterns(node)
 		}
 
 	case "function_definition":
@@ -127,7 +191,42 @@ func (py *Python) GetDecls(node *sitter.Node) []Decl {
 			if funcName != nil {
 				return []Decl{{funcName.Content(py.module.Source), node}}
 			}
-			// TODO@(Srijan/Tushar) bind function parameters
+		}
+
+	case "parameters", "lambda_parameters":
+		{
+			funcDef := node.Parent()
+			if funcDef == nil || !py.IsFunctionDef(funcDef) {
+				return nil
+			}
+
+			var decls []Decl
+
+			// The first parameter of a method is its receiver: an instance of the
+			// enclosing class (`self`), or the class itself in a classmethod (`cls`).
+			if receiver := py.receiverParamOf(funcDef); receiver != nil {
+				name := receiver.Content(py.module.Source)
+				if py.isClassMethod(funcDef) {
+					decls = append(decls, Decl{name, py.classOfMethod(funcDef)})
+				} else {
+					decls = append(decls, Decl{name, receiver})
+				}
+			}
+
+			// Other parameters are bound to their own identifiers, and the
+			// values passed to them are found by the call graph's value flow analysis.
+			for _, param := range py.ParametersOf(funcDef) {
+				decls = append(decls, Decl{param.Name, param.Node})
+			}
+
+			return decls
+		}
+
+	case "for_in_clause":
+		{
+			// The variables of a comprehension are bound in the comprehension's own
+			// scope. Like loop variables, they are bound to their own identifiers.
+			return py.bindPattern(node.ChildByFieldName("left"), nil)
 		}
 
 	case "class_definition":
@@ -140,6 +239,15 @@ func (py *Python) GetDecls(node *sitter.Node) []Decl {
 
 	case "import_from_statement":
 		{
+			// `from a import *` binds every public name of `a`, and is
+			// recorded in the scope as a single wildcard declaration.
+			wildcard := util.FindMatchingChild(node, func(child *sitter.Node) bool {
+				return child.Type() == "wildcard_import"
+			})
+			if wildcard != nil {
+				return []Decl{{WildcardName, node}}
+			}
+
 			importedSymbols := util.ChildrenWithFieldName(node, "name")
 			var decls []Decl
 			for _, nameNode := range importedSymbols {
@@ -158,8 +266,6 @@ func (py *Python) GetDecls(node *sitter.Node) []Decl {
 						name := aliasNode.Content(py.module.Source)
 						decls = append(decls, Decl{name, node})
 					}
-				default:
-					panic("Imported symbol is a " + nameNode.Type())
 				}
 			}
 
@@ -168,192 +274,1782 @@ func (py *Python) GetDecls(node *sitter.Node) []Decl {
 
 	case "import_statement":
 		{
-			// TODO: use children with field name and support multple.
-			module := node.ChildByFieldName("name")
-			if module.Type() == "dotted_name" {
-				name := module.Content(py.module.Source)
-				if strings.Contains(name, ".") {
-					// TODO: import dotted import statements (and handle in callgraph)
-				} else {
-					return []Decl{{name, node}}
+			var decls []Decl
+			for _, nameNode := range util.ChildrenWithFieldName(node, "name") {
+				// `import a.b.c` binds `a`, and `import a.b as c` binds `c`.
+				switch nameNode.Type() {
+				case "dotted_name":
+					firstChild := nameNode.NamedChild(0)
+					if firstChild != nil {
+						decls = append(decls, Decl{firstChild.Content(py.module.Source), node})
+					}
+				case "aliased_import":
+					aliasNode := nameNode.ChildByFieldName("alias")
+					if aliasNode != nil {
+						decls = append(decls, Decl{aliasNode.Content(py.module.Source), node})
+					}
 				}
 			}
+
+			return decls
 		}
 	}
 
 	return nil
 }
 
-func (py *Python) IsCallExpr(node *sitter.Node) bool {
-	return node.Type() == "call"
+// bindPattern binds the identifiers in an assignment target to the parts of the value
+// that they are assigned, so `a, (b, *c) = f, (g, h, i)` binds `a` to `f` and `b` to `g`.
+// Identifiers whose values can't be told apart (like starred targets, or targets that
+// unpack the result of a call) are bound to their own identifiers, the same way parameters
+// are. Attributes and subscripts (e.g: `a.b = f`) don't bind any names.
+func (py *Python) bindPattern(target, value *sitter.Node) []Decl {
+	var decls []Decl
+	py.forEachAssignedTarget(target, value, func(target, value *sitter.Node) {
+		if target.Type() != "identifier" {
+			return
+		}
+
+		if value == nil {
+			value = target
+		}
+		decls = append(decls, Decl{target.Content(py.module.Source), value})
+	})
+
+	return decls
 }
 
-func (py *Python) IsFunctionDef(node *sitter.Node) bool {
-	return node.Type() == "function_definition" || node.Type() == "lambda"
+// forEachAssignedTarget calls `visit` with every single target in an assignment target (like a
+// name, an attribute or a subscript), and the part of `value` that is assigned to it. The value
+// is nil when it isn't known (see `bindPattern`).
+func (py *Python) forEachAssignedTarget(target, value *sitter.Node, visit func(target, value *sitter.Node)) {
+	if target == nil {
+		return
+	}
+
+	switch target.Type() {
+	case "parenthesized_expression":
+		if target.NamedChildCount() == 1 {
+			py.forEachAssignedTarget(target.NamedChild(0), value, visit)
+		}
+
+	case "list_splat_pattern", "list_splat":
+		if target.NamedChildCount() > 0 {
+			py.forEachAssignedTarget(target.NamedChild(0), nil, visit)
+		}
+
+	case "pattern_list", "tuple_pattern", "list_pattern", "expression_list", "tuple", "list":
+		targets := namedChildrenExceptComments(target)
+		values := py.unpackedValuesOf(value, targets)
+		for i, elem := range targets {
+			var elemValue *sitter.Node
+			if values != nil {
+				elemValue = values[i]
+			}
+			py.forEachAssignedTarget(elem, elemValue, visit)
+		}
+
+	default:
+		visit(target, value)
+	}
 }
 
-func (py *Python) GetCalleeName(node *sitter.Node) *string {
-	function := node.ChildByFieldName("function")
-	if function == nil {
+// unpackedValuesOf returns the value that each one of `targets` gets when `value` is unpacked
+// into them, or nil if the values aren't known. Starred targets get a nil value.
+func (py *Python) unpackedValuesOf(value *sitter.Node, targets []*sitter.Node) []*sitter.Node {
+	if value == nil {
 		return nil
 	}
 
-	if function.Type() == "identifier" {
-		name := function.Content(py.module.Source)
-		return &name
+	switch value.Type() {
+	case "expression_list", "tuple", "list":
+	default:
+		return nil
 	}
 
-	return nil
-}
+	elems := namedChildrenExceptComments(value)
+	star := slices.IndexFunc(targets, func(target *sitter.Node) bool {
+		return target.Type() == "list_splat_pattern" || target.Type() == "list_splat"
+	})
 
-func (py *Python) BodyOfFunction(node *sitter.Node) *sitter.Node {
-	typ := node.Type()
-	if typ != "function_definition" && typ != "lambda" {
+	if (star < 0 && len(elems) != len(targets)) || len(elems) < len(targets)-1 {
 		return nil
 	}
 
-	return node.ChildByFieldName("body")
+	values := make([]*sitter.Node, len(targets))
+	for i := range targets {
+		if star < 0 || i < star {
+			values[i] = elems[i]
+		} else if i > star {
+			// Targets after the starred one are matched from the end
+			values[i] = elems[len(elems)-(len(targets)-i)]
+		}
+	}
+
+	return values
 }
 
-func (py *Python) NameOfFunction(node *sitter.Node) *string {
-	if node.Type() == "function_definition" {
-		name := node.ChildByFieldName("name").Content(py.module.Source)
-		return &name
+// namedChildrenExceptComments returns the named children of a node, without comments.
+func namedChildrenExceptComments(node *sitter.Node) []*sitter.Node {
+	var children []*sitter.Node
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		if child := node.NamedChild(i); child.Type() != "comment" {
+			children = append(children, child)
+		}
 	}
 
-	if node.Type() == "lambda" {
-		nearestScope := GetScope(py.Module(), node)
-		name := nearestScope.NameOfNode[node]
-		return &name
+	return children
+}
+
+// asPatternOf returns the expression and the target of an `as_pattern`, like `a` and `b` in `a as b`.
+// For any other node, the node itself is returned along with a nil target.
+func (py *Python) asPatternOf(node *sitter.Node) (*sitter.Node, *sitter.Node) {
+	if node == nil || node.Type() != "as_pattern" {
+		return node, nil
 	}
 
-	return nil
-}
+	var expr, target *sitter.Node
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+		if child.Type() == "as_pattern_target" {
+			target = child.NamedChild(0)
+		} else if expr == nil {
+			expr = child
+		}
+	}
 
-func (py *Python) IsImport(node *sitter.Node) bool {
-	return node.Type() == "import_from_statement" || node.Type() == "import_statement"
+	return expr, target
 }
 
-func (py *Python) IsModuleImport(node *sitter.Node) bool {
-	return node.Type() == "import_statement"
-}
+// bindCasePatterns binds the names captured by the patterns of a `case` clause.
+// A `case x:` clause binds `x` to the subject of the `match` statement, and any
+// other captured names are bound to their own identifiers.
+func (py *Python) bindCasePatterns(caseClause *sitter.Node) []Decl {
+	var patterns []*sitter.Node
+	for i := 0; i < int(caseClause.NamedChildCount()); i++ {
+		if child := caseClause.NamedChild(i); child.Type() == "case_pattern" {
+			patterns = append(patterns, child)
+		}
+	}
 
-func (py *Python) FilePathOfImport(node *sitter.Node) *string {
-	cached := py.module.FilePathOfImport[node]
-	if cached != "" {
-		return &cached
+	var subject *sitter.Node
+	if block := caseClause.Parent(); block != nil && block.Parent() != nil {
+		subject = block.Parent().ChildByFieldName("subject")
 	}
 
-	// TODO(@Tushar/Srijan): `import *` is not handled
-	var moduleName string
-	var itemName string
-	upLevel := 0
-	if node.Type() == "import_from_statement" {
-		moduleName = node.ChildByFieldName("module_name").Content(py.module.Source)
-		for strings.HasPrefix(moduleName, ".") {
-			moduleName = moduleName[1:]
-			upLevel++
+	var decls []Decl
+	for _, pattern := range patterns {
+		captures := py.capturesOf(pattern)
+		if len(patterns) == 1 && len(captures) == 1 && subject != nil && pattern.NamedChildCount() == 1 &&
+			pattern.NamedChild(0).Type() == "dotted_name" {
+			return []Decl{{captures[0].Content(py.module.Source), subject}}
+		}
+
+		for _, capture := range captures {
+			decls = append(decls, Decl{capture.Content(py.module.Source), capture})
+		}
+	}
+
+	return decls
+}
+
+// capturesOf returns the identifiers that a pattern of a `case` clause captures.
+func (py *Python) capturesOf(pattern *sitter.Node) []*sitter.Node {
+	switch pattern.Type() {
+	case "identifier":
+		// `_` is a wildcard, and doesn't capture anything
+		if pattern.Content(py.module.Source) == "_" {
+			return nil
+		}
+		return []*sitter.Node{pattern}
+
+	case "dotted_name":
+		// A single name captures the value, but a dotted
+		// name is compared against it (e.g: `Color.RED`).
+		if pattern.NamedChildCount() != 1 {
+			return nil
 		}
+		return py.capturesOf(pattern.NamedChild(0))
 
-		// TODO: there are multiple `name` field here, right now we just use the
-		// first one. refer to the TODO in `language.go` about `FilePathOfImport()`,
-		// we should build a map of import node to filepath instead.
-		// To do that we can use the ChildrenWithFieldName helper here.
-		itemName = node.ChildByFieldName("name").Content(py.module.Source)
+	case "class_pattern", "keyword_pattern":
+		// The class in `Point(x=a)` and the keyword `x` aren't captures
+		var captures []*sitter.Node
+		for i := 1; i < int(pattern.NamedChildCount()); i++ {
+			captures = append(captures, py.capturesOf(pattern.NamedChild(i))...)
+		}
+		return captures
+
+	case "string", "integer", "float", "true", "false", "none", "comment":
+		return nil
+	}
 
-	} else if node.Type() == "import_statement" {
-		moduleName = node.ChildByFieldName("name").Content(py.module.Source)
-		for strings.HasPrefix(moduleName, ".") {
-			moduleName = moduleName[1:]
-			upLevel++
+	var captures []*sitter.Node
+	for i := 0; i < int(pattern.ChildCount()); i++ {
+		// The keys of a mapping pattern are compared against, and aren't captures
+		child := pattern.Child(i)
+		if !child.IsNamed() || pattern.FieldNameForChild(i) == "key" {
+			continue
 		}
+		captures = append(captures, py.capturesOf(child)...)
+	}
+
+	return captures
+}
 
-		itemName = ""
+func (py *Python) GlobalNamesOf(node *sitter.Node) []string {
+	if node.Type() != "global_statement" {
+		return nil
 	}
 
-	baseModulePath := filepath.Join(strings.Split(moduleName, ".")...)
+	return py.namesIn(node)
+}
 
-	relPaths := []string{".", "src"}
-	var rootPath string
-	if upLevel > 0 {
-		rootPath = py.Module().FileName
-		for i := 0; i < upLevel; i++ {
-			rootPath = filepath.Dir(rootPath)
+func (py *Python) NonlocalNamesOf(node *sitter.Node) []string {
+	if node.Type() != "nonlocal_statement" {
+		return nil
+	}
+
+	return py.namesIn(node)
+}
+
+// IsEvaluatedInEnclosingScope returns `true` for the base classes of a class, the default values
+// and annotations of a function's parameters, its return type annotation, and the iterable of
+// the first `for` clause of a comprehension.
+func (py *Python) IsEvaluatedInEnclosingScope(node *sitter.Node) bool {
+	parent := node.Parent()
+	if parent == nil {
+		return false
+	}
+
+	switch parent.Type() {
+	case "class_definition":
+		return parent.ChildByFieldName("superclasses") == node
+
+	case "function_definition":
+		return parent.ChildByFieldName("return_type") == node
+
+	case "default_parameter", "typed_default_parameter", "typed_parameter":
+		return parent.ChildByFieldName("value") == node || parent.ChildByFieldName("type") == node
+
+	case "for_in_clause":
+		comprehension := parent.Parent()
+		if comprehension == nil || !py.IsComprehension(comprehension) || parent.ChildByFieldName("right") != node {
+			return false
 		}
-	} else {
-		rootPath = *py.Module().ProjectRoot
-	}
-	modulePaths := []string{baseModulePath}
-	if itemName != "" {
-		modulePaths = append(modulePaths, filepath.Join(baseModulePath, itemName))
-	}
-
-	for _, relPath := range relPaths {
-		for _, modulePath := range modulePaths {
-			// fmt.Fprintf(os.Stderr, "%v  %v  %v\n", py.SitePackagesPath, relPath, modulePath)
-			possibleFiles := []string{
-				filepath.Join(rootPath, relPath, modulePath, "__init__.py"),
-				filepath.Join(rootPath, relPath, modulePath+".py"),
-				filepath.Join(py.SitePackagesPath, relPath, modulePath, "__init__.py"),
-				filepath.Join(py.SitePackagesPath, relPath, modulePath+".py"),
-			}
-			for _, possibleFile := range possibleFiles {
-				if _, err := os.Stat(possibleFile); err == nil {
-					py.module.FilePathOfImport[node] = possibleFile
-					return &possibleFile
-				}
-			}
+
+		firstClause := util.FindMatchingChild(comprehension, func(child *sitter.Node) bool {
+			return child.Type() == "for_in_clause"
+		})
+		return firstClause == parent
+	}
+
+	return false
+}
+
+func (py *Python) IsComprehension(node *sitter.Node) bool {
+	switch node.Type() {
+	case "list_comprehension", "set_comprehension", "dictionary_comprehension", "generator_expression":
+		return true
+	}
+
+	return false
+}
+
+func (py *Python) IsAssignmentExpr(node *sitter.Node) bool {
+	return node.Type() == "named_expression"
+}
+
+// namesIn returns the names of the identifiers that are children of a node.
+func (py *Python) namesIn(node *sitter.Node) []string {
+	var names []string
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		if child := node.NamedChild(i); child.Type() == "identifier" {
+			names = append(names, child.Content(py.module.Source))
 		}
 	}
 
-	return nil
+	return names
 }
 
-func (py *Python) IsDottedExpr(node *sitter.Node) bool {
-	return node.Type() == "attribute"
+func (py *Python) IsCallExpr(node *sitter.Node) bool {
+	return node.Type() == "call"
 }
 
-func (py *Python) GetCallee(callExpr *sitter.Node) *sitter.Node {
-	return callExpr.ChildByFieldName("function")
+func (py *Python) IsFunctionDef(node *sitter.Node) bool {
+	return node.Type() == "function_definition" || node.Type() == "lambda"
 }
 
-func (py *Python) FunctionDefFromNode(node *sitter.Node) *sitter.Node {
-	if node.Type() != "class_definition" {
+// classOfMethod returns the class that `funcDef` is defined in, or nil
+// if `funcDef` isn't defined directly in the body of a class.
+func (py *Python) classOfMethod(funcDef *sitter.Node) *sitter.Node {
+	if funcDef == nil || funcDef.Type() != "function_definition" {
 		return nil
 	}
 
-	// TODO: super calls?
-	body := node.ChildByFieldName("body")
-	// TODO: make this a reverse iterator instead to find the last __init__
-	// because @overload's exist
-	return util.FindMatchingChild(body, func(child *sitter.Node) bool {
-		if child.Type() != "function_definition" {
-			return false
-		}
+	parent := funcDef.Parent()
+	if parent != nil && parent.Type() == "decorated_definition" {
+		parent = parent.Parent()
+	}
 
-		funcName := child.ChildByFieldName("name")
-		if funcName == nil {
-			return false
+	if parent == nil || parent.Type() != "block" {
+		return nil
+	}
+
+	class := parent.Parent()
+	if class == nil || !py.IsClassDef(class) {
+		return nil
+	}
+
+	return class
+}
+
+// decoratorNamesOf returns the names of the plain decorators
+// applied to a definition (e.g: `staticmethod` for `@staticmethod`).
+func (py *Python) decoratorNamesOf(def *sitter.Node) []string {
+	decorated := def.Parent()
+	if decorated == nil || decorated.Type() != "decorated_definition" {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < int(decorated.NamedChildCount()); i++ {
+		decorator := decorated.NamedChild(i)
+		if decorator.Type() != "decorator" || decorator.NamedChildCount() == 0 {
+			continue
 		}
 
-		return funcName.Content(py.module.Source) == "__init__"
-	})
+		names = append(names, decorator.NamedChild(0).Content(py.module.Source))
+	}
+
+	return names
 }
 
-func (py *Python) ResolveExportedSymbol(name string) *sitter.Node {
-	globalScope := py.Module().GlobalScope
-	return globalScope.Symbols[name]
+// isClassMethod returns `true` if the receiver of a method is its class, rather than an instance.
+// `__new__`, `__init_subclass__` and `__class_getitem__` are implicitly classmethods.
+func (py *Python) isClassMethod(funcDef *sitter.Node) bool {
+	if slices.Contains(py.decoratorNamesOf(funcDef), "classmethod") {
+		return true
+	}
+
+	name := funcDef.ChildByFieldName("name")
+	if name == nil {
+		return false
+	}
+
+	switch name.Content(py.module.Source) {
+	case "__new__", "__init_subclass__", "__class_getitem__":
+		return true
+	}
+
+	return false
 }
 
-func (py *Python) GetObjectAndProperty(node *sitter.Node) (*sitter.Node, *sitter.Node) {
-	return node.ChildByFieldName("object"), node.ChildByFieldName("attribute")
+// receiverParamOf returns the identifier of a method's first parameter (like `self` or `cls`).
+// Returns nil for functions that aren't methods, and for static methods.
+func (py *Python) receiverParamOf(funcDef *sitter.Node) *sitter.Node {
+	if py.classOfMethod(funcDef) == nil || slices.Contains(py.decoratorNamesOf(funcDef), "staticmethod") {
+		return nil
+	}
+
+	params := funcDef.ChildByFieldName("parameters")
+	if params == nil || params.NamedChildCount() == 0 {
+		return nil
+	}
+
+	switch param := params.NamedChild(0); param.Type() {
+	case "identifier":
+		return param
+	case "typed_parameter":
+		return util.FindMatchingChild(param, func(child *sitter.Node) bool {
+			return child.Type() == "identifier"
+		})
+	case "default_parameter", "typed_default_parameter":
+		return param.ChildByFieldName("name")
+	}
+
+	return nil
 }
 
-func (py *Python) PackageName() *string {
-	if py.module.ProjectRoot == nil {
+// ReceiverClassOf returns the class that `node` is an instance of,
+// if `node` is the `self` parameter of a method.
+func (py *Python) ReceiverClassOf(node *sitter.Node) *sitter.Node {
+	params := node.Parent()
+	if params != nil && params.Type() != "parameters" {
+		params = params.Parent()
+	}
+
+	if params == nil || params.Type() != "parameters" {
 		return nil
 	}
 
-	baseName := filepath.Base(*py.module.ProjectRoot)
-	return &baseName
+	funcDef := params.Parent()
+	if py.receiverParamOf(funcDef) != node || py.isClassMethod(funcDef) {
+		return nil
+	}
+
+	return py.classOfMethod(funcDef)
+}
+
+func (py *Python) SuperclassesOf(class *sitter.Node) []*sitter.Node {
+	argList := class.ChildByFieldName("superclasses")
+	if argList == nil {
+		return nil
+	}
+
+	var bases []*sitter.Node
+	for i := 0; i < int(argList.NamedChildCount()); i++ {
+		// keyword arguments (like `metaclass=ABCMeta`) and splats aren't bases
+		arg := argList.NamedChild(i)
+		switch arg.Type() {
+		case "keyword_argument", "list_splat", "dictionary_splat", "comment":
+			continue
+		}
+
+		bases = append(bases, arg)
+	}
+
+	return bases
+}
+
+// SuperCallOwner returns the first argument of `super(Class, obj)`, or the class that a
+// zero-argument `super()` call is made in (python passes it implicitly).
+func (py *Python) SuperCallOwner(node *sitter.Node) *sitter.Node {
+	if node.Type() != "call" {
+		return nil
+	}
+
+	callee := node.ChildByFieldName("function")
+	if callee == nil || callee.Type() != "identifier" || callee.Content(py.module.Source) != "super" {
+		return nil
+	}
+
+	// `super` is a builtin, unless it's shadowed by a local definition.
+	if scope := GetScope(py.module, node); scope == nil || scope.Lookup("super") != nil {
+		return nil
+	}
+
+	args := node.ChildByFieldName("arguments")
+	if args != nil && args.NamedChildCount() > 0 {
+		return args.NamedChild(0)
+	}
+
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.Type() == "function_definition" {
+			return py.classOfMethod(parent)
+		}
+	}
+
+	return nil
+}
+
+func (py *Python) IsClassDef(node *sitter.Node) bool {
+	return node.Type() == "class_definition"
+}
+
+// IsDecoratorCall returns `true` for decorators that aren't call expressions themselves (like `@cache`).
+// Decorators like `@app.route("/")` are already call expressions, and are visited as such.
+func (py *Python) IsDecoratorCall(node *sitter.Node) bool {
+	if node.Type() != "decorator" || node.NamedChildCount() == 0 {
+		return false
+	}
+
+	return !py.IsCallExpr(node.NamedChild(0))
+}
+
+func (py *Python) GetCalleeName(node *sitter.Node) *string {
+	function := py.GetCallee(node)
+	if function == nil {
+		return nil
+	}
+
+	if function.Type() == "identifier" {
+		name := function.Content(py.module.Source)
+		return &name
+	}
+
+	return nil
+}
+
+func (py *Python) ArgumentsOf(callExpr *sitter.Node) []Argument {
+	argList := callExpr.ChildByFieldName("arguments")
+	if argList == nil || argList.Type() != "argument_list" {
+		return nil
+	}
+
+	var args []Argument
+	for i := 0; i < int(argList.NamedChildCount()); i++ {
+		arg := Argument{Value: argList.NamedChild(i)}
+		switch arg.Value.Type() {
+		case "keyword_argument":
+			if name := arg.Value.ChildByFieldName("name"); name != nil {
+				arg.Keyword = name.Content(py.module.Source)
+			}
+			arg.Value = arg.Value.ChildByFieldName("value")
+		case "list_splat", "dictionary_splat":
+			arg.IsSplat = true
+			arg.Value = arg.Value.NamedChild(0)
+		case "comment":
+			continue
+		}
+
+		if arg.Value != nil {
+			args = append(args, arg)
+		}
+	}
+
+	return args
+}
+
+// ParametersOf returns the parameters of a function definition, in order.
+// The receiver of a method (`self` or `cls`) is left out, since
+// arguments are never passed to it explicitly when the method is called.
+func (py *Python) ParametersOf(funcDef *sitter.Node) []Parameter {
+	if !py.IsFunctionDef(funcDef) {
+		return nil
+	}
+
+	paramList := funcDef.ChildByFieldName("parameters")
+	if paramList == nil {
+		return nil
+	}
+
+	receiver := py.receiverParamOf(funcDef)
+	isKeywordOnly := false
+
+	var params []Parameter
+	for i := 0; i < int(paramList.NamedChildCount()); i++ {
+		paramNode := paramList.NamedChild(i)
+		param := Parameter{IsKeywordOnly: isKeywordOnly}
+
+		// `*args: int` is a typed parameter that wraps a splat pattern
+		if paramNode.Type() == "typed_parameter" && paramNode.NamedChildCount() > 0 {
+			if inner := paramNode.NamedChild(0); inner.Type() != "identifier" {
+				paramNode = inner
+			}
+		}
+
+		switch paramNode.Type() {
+		case "identifier":
+			param.Node = paramNode
+		case "typed_parameter":
+			param.Node = paramNode.NamedChild(0)
+		case "default_parameter", "typed_default_parameter":
+			param.Node = paramNode.ChildByFieldName("name")
+			param.Default = paramNode.ChildByFieldName("value")
+		case "list_splat_pattern", "dictionary_splat_pattern":
+			// parameters after `*args` can only be passed by keyword
+			param.Node = paramNode.NamedChild(0)
+			param.IsVariadic = true
+			isKeywordOnly = true
+		case "keyword_separator":
+			isKeywordOnly = true
+			continue
+		default:
+			continue
+		}
+
+		if param.Node == nil || param.Node.Type() != "identifier" || param.Node == receiver {
+			continue
+		}
+
+		param.Name = param.Node.Content(py.module.Source)
+		params = append(params, param)
+	}
+
+	return params
+}
+
+// ReturnedExprsOf returns the expressions that a function returns, including the
+// body of a lambda. Return statements in nested functions and classes are skipped.
+func (py *Python) ReturnedExprsOf(fn *sitter.Node) []*sitter.Node {
+	if fn.Type() == "lambda" {
+		if body := fn.ChildByFieldName("body"); body != nil {
+			return []*sitter.Node{body}
+		}
+		return nil
+	}
+
+	var exprs []*sitter.Node
+	var findReturns func(node *sitter.Node)
+	findReturns = func(node *sitter.Node) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			if py.IsFunctionDef(child) || py.IsClassDef(child) {
+				continue
+			}
+
+			if child.Type() == "return_statement" && child.NamedChildCount() > 0 {
+				exprs = append(exprs, child.NamedChild(0))
+				continue
+			}
+
+			findReturns(child)
+		}
+	}
+
+	if body := py.BodyOfFunction(fn); body != nil {
+		findReturns(body)
+	}
+
+	return exprs
+}
+
+func (py *Python) ElementsOf(node *sitter.Node) []*sitter.Node {
+	var elems []*sitter.Node
+	switch node.Type() {
+	case "list", "tuple", "set", "expression_list":
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			if elem := node.NamedChild(i); elem.Type() != "comment" {
+				elems = append(elems, elem)
+			}
+		}
+	case "dictionary":
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			if value := node.NamedChild(i).ChildByFieldName("value"); value != nil {
+				elems = append(elems, value)
+			}
+		}
+	case "parenthesized_expression":
+		if node.NamedChildCount() > 0 {
+			return py.ElementsOf(node.NamedChild(0))
+		}
+	}
+
+	return elems
+}
+
+func (py *Python) LoopIterableOf(idNode *sitter.Node) *sitter.Node {
+	if idNode.Type() != "identifier" {
+		return nil
+	}
+
+	name := idNode.Content(py.module.Source)
+	for node := idNode.Parent(); node != nil; node = node.Parent() {
+		if py.IsFunctionDef(node) || py.IsClassDef(node) {
+			return nil
+		}
+
+		if node.Type() != "for_statement" {
+			continue
+		}
+
+		target := node.ChildByFieldName("left")
+		if target != nil && target.Type() == "identifier" && target.Content(py.module.Source) == name {
+			return node.ChildByFieldName("right")
+		}
+	}
+
+	return nil
+}
+
+// binaryOperatorMethods maps the operators of binary expressions
+// to the methods of the left operand that implement them.
+var binaryOperatorMethods = map[string]string{
+	"+":  "__add__",
+	"-":  "__sub__",
+	"*":  "__mul__",
+	"@":  "__matmul__",
+	"/":  "__truediv__",
+	"//": "__floordiv__",
+	"%":  "__mod__",
+	"**": "__pow__",
+	"<<": "__lshift__",
+	">>": "__rshift__",
+	"&":  "__and__",
+	"|":  "__or__",
+	"^":  "__xor__",
+}
+
+func (py *Python) ImplicitCallsOf(node *sitter.Node) []ImplicitCall {
+	switch node.Type() {
+	case "with_item":
+		manager := node.ChildByFieldName("value")
+		// `with a as b` is parsed as an `as_pattern`, whose first child is the context manager
+		if manager != nil && manager.Type() == "as_pattern" {
+			manager = manager.NamedChild(0)
+		}
+
+		if manager == nil {
+			return nil
+		}
+
+		withStmt := node.Parent()
+		if withStmt != nil {
+			withStmt = withStmt.Parent()
+		}
+
+		if withStmt != nil && hasAsyncKeyword(withStmt) {
+			return []ImplicitCall{{Receiver: manager, Method: "__aenter__"}, {Receiver: manager, Method: "__aexit__"}}
+		}
+		return []ImplicitCall{{Receiver: manager, Method: "__enter__"}, {Receiver: manager, Method: "__exit__"}}
+
+	case "for_statement", "for_in_clause":
+		iterable := node.ChildByFieldName("right")
+		if iterable == nil {
+			return nil
+		}
+
+		if hasAsyncKeyword(node) {
+			return []ImplicitCall{{Receiver: iterable, Method: "__aiter__", OnResult: "__anext__"}}
+		}
+		return []ImplicitCall{{Receiver: iterable, Method: "__iter__", OnResult: "__next__"}}
+
+	case "subscript":
+		object := node.ChildByFieldName("value")
+		if object == nil {
+			return nil
+		}
+
+		switch accessKindOf(node) {
+		case accessWrite:
+			return []ImplicitCall{{Receiver: object, Method: "__setitem__"}}
+		case accessReadWrite:
+			return []ImplicitCall{{Receiver: object, Method: "__getitem__"}, {Receiver: object, Method: "__setitem__"}}
+		case accessDelete:
+			return []ImplicitCall{{Receiver: object, Method: "__delitem__"}}
+		default:
+			return []ImplicitCall{{Receiver: object, Method: "__getitem__"}}
+		}
+
+	case "binary_operator":
+		left, operator := node.ChildByFieldName("left"), node.ChildByFieldName("operator")
+		if left == nil || operator == nil {
+			return nil
+		}
+
+		method, found := binaryOperatorMethods[operator.Type()]
+		if !found {
+			return nil
+		}
+		return []ImplicitCall{{Receiver: left, Method: method}}
+
+	case "attribute":
+		// A called attribute is resolved as a callee, and an assigned or deleted attribute isn't read.
+		parent := node.Parent()
+		if parent != nil && parent.Type() == "call" && parent.ChildByFieldName("function") == node {
+			return nil
+		}
+
+		if kind := accessKindOf(node); kind == accessWrite || kind == accessDelete {
+			return nil
+		}
+
+		object, property := py.GetObjectAndProperty(node)
+		if object == nil || property == nil {
+			return nil
+		}
+		return []ImplicitCall{{Receiver: object, Method: property.Content(py.module.Source), PropertyOnly: true}}
+	}
+
+	return nil
+}
+
+// accessKind is how a statement uses an expression
+type accessKind int
+
+const (
+	accessRead accessKind = iota
+	accessWrite
+	// accessReadWrite is used by augmented assignments (e.g: `a[0] += 1`)
+	accessReadWrite
+	accessDelete
+)
+
+// accessKindOf returns whether an expression is read, assigned to or deleted.
+func accessKindOf(node *sitter.Node) accessKind {
+	// Targets can be unpacked (e.g: `a[0], b = c`) or deleted together (e.g: `del a[0], b`)
+	parent := node.Parent()
+	for parent != nil {
+		switch parent.Type() {
+		case "pattern_list", "tuple_pattern", "list_pattern", "expression_list":
+			node, parent = parent, parent.Parent()
+			continue
+		}
+		break
+	}
+
+	if parent == nil {
+		return accessRead
+	}
+
+	switch parent.Type() {
+	case "assignment", "for_statement", "for_in_clause":
+		if parent.ChildByFieldName("left") == node {
+			return accessWrite
+		}
+	case "augmented_assignment":
+		if parent.ChildByFieldName("left") == node {
+			return accessReadWrite
+		}
+	case "delete_statement":
+		return accessDelete
+	}
+
+	return accessRead
+}
+
+// hasAsyncKeyword returns `true` for `async` statements, like `async with` and `async for`.
+func hasAsyncKeyword(node *sitter.Node) bool {
+	for i := 0; i < int(node.ChildCount()); i++ {
+		if node.Child(i).Type() == "async" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsPropertyGetter returns `true` for methods that are decorated with `@property` or `@cached_property`.
+func (py *Python) IsPropertyGetter(node *sitter.Node) bool {
+	if node.Type() != "function_definition" {
+		return false
+	}
+
+	for _, name := range py.decoratorNamesOf(node) {
+		switch name {
+		case "property", "cached_property", "functools.cached_property":
+			return true
+		}
+	}
+
+	return false
+}
+
+// TypeAnnotationOf returns the type annotation of a parameter (given its identifier),
+// or the return type annotation of a function definition.
+// The annotation of a variadic parameter (e.g: `*args: int`) is the type of each argument,
+// rather than the type of the parameter, so it is left out.
+func (py *Python) TypeAnnotationOf(node *sitter.Node) *sitter.Node {
+	if node.Type() == "function_definition" {
+		return node.ChildByFieldName("return_type")
+	}
+
+	param := node.Parent()
+	if node.Type() != "identifier" || param == nil {
+		return nil
+	}
+
+	switch param.Type() {
+	case "typed_parameter":
+		if param.NamedChild(0) == node {
+			return param.ChildByFieldName("type")
+		}
+	case "typed_default_parameter":
+		if param.ChildByFieldName("name") == node {
+			return param.ChildByFieldName("type")
+		}
+	}
+
+	return nil
+}
+
+// AnnotatedTypesOf returns the dotted names of the classes that a type annotation says a value
+// is an instance of (e.g: `httpx.Client`). Every member of a union is returned, except `None`,
+// so `Optional[A]`, `A | None` and `Union[A, None]` are all `A`. String annotations (like
+// `"Session"`) are parsed as expressions. Returns nil if the node isn't a type annotation.
+func (py *Python) AnnotatedTypesOf(annotation *sitter.Node) []string {
+	if annotation.Type() != "type" {
+		return nil
+	}
+
+	if names, cached := py.annotatedTypes[annotation]; cached {
+		return names
+	}
+
+	names := typeNamesIn(annotation, py.module.Source)
+	py.annotatedTypes[annotation] = names
+	return names
+}
+
+// typeNamesIn returns the names of the classes that a type expression refers to (see `AnnotatedTypesOf`).
+func typeNamesIn(node *sitter.Node, source []byte) []string {
+	switch node.Type() {
+	case "type":
+		if node.NamedChildCount() > 0 {
+			return typeNamesIn(node.NamedChild(0), source)
+		}
+
+	case "identifier", "attribute":
+		if name := dottedNameOf(node, source); name != "" {
+			return []string{name}
+		}
+
+	case "generic_type":
+		// `list[int]` and `Optional[A]` in annotations
+		var args []*sitter.Node
+		if params := util.FindMatchingChild(node, func(child *sitter.Node) bool {
+			return child.Type() == "type_parameter"
+		}); params != nil {
+			args = namedChildrenExceptComments(params)
+		}
+		return genericTypeNames(node.NamedChild(0), args, source)
+
+	case "subscript":
+		// `Union[A, B]` and generics in string annotations
+		args := util.ChildrenWithFieldName(node, "subscript")
+		return genericTypeNames(node.ChildByFieldName("value"), args, source)
+
+	case "binary_operator":
+		// `A | B`
+		operator := node.ChildByFieldName("operator")
+		if operator != nil && operator.Type() == "|" {
+			left := typeNamesIn(node.ChildByFieldName("left"), source)
+			return append(left, typeNamesIn(node.ChildByFieldName("right"), source)...)
+		}
+
+	case "string":
+		// Forward references, like `"Session"` or `"Optional[Session]"`
+		var content []byte
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			switch child := node.NamedChild(i); child.Type() {
+			case "string_content":
+				content = append(content, child.Content(source)...)
+			case "string_start", "string_end":
+				continue
+			default:
+				return nil
+			}
+		}
+
+		ast, err := sitter.ParseCtx(context.Background(), content, treeSitterPy.GetLanguage())
+		if err != nil || ast.NamedChildCount() != 1 {
+			return nil
+		}
+
+		stmt := ast.NamedChild(0)
+		if stmt.Type() != "expression_statement" || stmt.NamedChildCount() != 1 {
+			return nil
+		}
+
+		return typeNamesIn(stmt.NamedChild(0), content)
+	}
+
+	return nil
+}
+
+// genericTypeNames returns the names of the classes that a generic type (e.g: `Optional[A]`)
+// refers to, given the expression for its base type and its type arguments.
+func genericTypeNames(base *sitter.Node, args []*sitter.Node, source []byte) []string {
+	if base == nil {
+		return nil
+	}
+
+	baseName := dottedNameOf(base, source)
+	if baseName == "" {
+		return nil
+	}
+
+	unqualified := baseName[strings.LastIndex(baseName, ".")+1:]
+	switch unqualified {
+	case "Optional", "Union":
+		var names []string
+		for _, arg := range args {
+			names = append(names, typeNamesIn(arg, source)...)
+		}
+		return names
+
+	case "Annotated", "ClassVar", "Final", "Required", "NotRequired", "ReadOnly":
+		// These qualify the type that is their first argument
+		if len(args) > 0 {
+			return typeNamesIn(args[0], source)
+		}
+		return nil
+
+	case "Type":
+		// `Type[A]` is the class `A`, not an instance of it
+		return nil
+	}
+
+	// Any other generic (like `list[int]`) is an instance of its base type
+	return []string{baseName}
+}
+
+// dottedNameOf returns the dotted name that an identifier, or a chain of attributes
+// on an identifier, spells out (e.g: `httpx.Client`). Returns "" for any other node.
+func dottedNameOf(node *sitter.Node, source []byte) string {
+	switch node.Type() {
+	case "identifier":
+		return node.Content(source)
+	case "attribute":
+		object := dottedNameOf(node.ChildByFieldName("object"), source)
+		attribute := node.ChildByFieldName("attribute")
+		if object == "" || attribute == nil {
+			return ""
+		}
+		return object + "." + attribute.Content(source)
+	}
+
+	return ""
+}
+
+// InstanceAttributesOf returns the attributes that the methods of a class assign on their receiver
+// (e.g: `self.session = requests.Session()`), mapped to the values that they are assigned in
+// source order. Annotated attributes (e.g: `self.session: Session`) are also bound to their
+// type annotations. Values that aren't known (like the ones unpacked from a call) are left out.
+func (py *Python) InstanceAttributesOf(class *sitter.Node) map[string][]*sitter.Node {
+	if attributes, cached := py.instanceAttributes[class]; cached {
+		return attributes
+	}
+
+	attributes := make(map[string][]*sitter.Node)
+	py.instanceAttributes[class] = attributes
+
+	body := class.ChildByFieldName("body")
+	if body == nil {
+		return attributes
+	}
+
+	for _, stmt := range namedChildrenExceptComments(body) {
+		method := stmt
+		if method.Type() == "decorated_definition" {
+			method = method.ChildByFieldName("definition")
+		}
+
+		if method == nil || method.Type() != "function_definition" || py.isClassMethod(method) {
+			continue
+		}
+
+		receiver := py.receiverParamOf(method)
+		if receiver == nil {
+			continue
+		}
+
+		py.addInstanceAttributes(attributes, method.ChildByFieldName("body"), receiver.Content(py.module.Source))
+	}
+
+	return attributes
+}
+
+// addInstanceAttributes adds the attributes that the statements in `node` assign on
+// the receiver named `receiver`. Nested functions and classes are skipped, since their
+// receivers (if any) are other objects.
+func (py *Python) addInstanceAttributes(attributes map[string][]*sitter.Node, node *sitter.Node, receiver string) {
+	if node == nil {
+		return
+	}
+
+	for _, child := range namedChildrenExceptComments(node) {
+		switch child.Type() {
+		case "function_definition", "class_definition", "decorated_definition", "lambda":
+			continue
+		case "assignment":
+			target, value := child.ChildByFieldName("left"), child.ChildByFieldName("right")
+			for value != nil && value.Type() == "assignment" && value.ChildByFieldName("right") != nil {
+				value = value.ChildByFieldName("right")
+			}
+
+			py.forEachAssignedTarget(target, value, func(target, value *sitter.Node) {
+				if name := py.receiverAttributeName(target, receiver); name != "" && value != nil {
+					attributes[name] = append(attributes[name], value)
+				}
+			})
+
+			annotation := child.ChildByFieldName("type")
+			if name := py.receiverAttributeName(target, receiver); name != "" && annotation != nil {
+				attributes[name] = append(attributes[name], annotation)
+			}
+		}
+
+		py.addInstanceAttributes(attributes, child, receiver)
+	}
+}
+
+// receiverAttributeName returns the name of the attribute that an assignment target sets on the
+// receiver named `receiver` (e.g: `session` for `self.session`), or "" for any other target.
+func (py *Python) receiverAttributeName(target *sitter.Node, receiver string) string {
+	if target == nil || target.Type() != "attribute" {
+		return ""
+	}
+
+	object, attribute := py.GetObjectAndProperty(target)
+	if object == nil || attribute == nil || object.Type() != "identifier" ||
+		object.Content(py.module.Source) != receiver {
+		return ""
+	}
+
+	return attribute.Content(py.module.Source)
+}
+
+func (py *Python) BodyOfFunction(node *sitter.Node) *sitter.Node {
+	typ := node.Type()
+	if typ != "function_definition" && typ != "lambda" {
+		return nil
+	}
+
+	return node.ChildByFieldName("body")
+}
+
+func (py *Python) NameOfFunction(node *sitter.Node) *string {
+	if node.Type() == "function_definition" {
+		name := node.ChildByFieldName("name").Content(py.module.Source)
+		return &name
+	}
+
+	if node.Type() == "lambda" {
+		// A lambda introduces its own scope, and its name is bound in the enclosing one
+		nearestScope := GetScope(py.Module(), node.Parent())
+		name := nearestScope.NameOfNode[node]
+		return &name
+	}
+
+	return nil
+}
+
+func (py *Python) IsImport(node *sitter.Node) bool {
+	return node.Type() == "import_from_statement" || node.Type() == "import_statement"
+}
+
+func (py *Python) IsModuleImport(node *sitter.Node) bool {
+	return node.Type() == "import_statement"
+}
+
+func (py *Python) ImportTargetOf(node *sitter.Node, name string) *ImportTarget {
+	key := ImportedName{Node: node, Name: name}
+	if cached, exists := py.module.ImportTargets[key]; exists {
+		return cached
+	}
+
+	target := py.resolveImportedName(node, name)
+	py.module.ImportTargets[key] = target
+	return target
+}
+
+// resolveImportedName finds the target of a single name bound by an import statement.
+func (py *Python) resolveImportedName(node *sitter.Node, name string) *ImportTarget {
+	switch node.Type() {
+	case "import_statement":
+		moduleName := py.moduleBoundBy(node, name)
+		if moduleName == nil {
+			return nil
+		}
+
+		filePath, _ := py.findModule(*moduleName)
+		if filePath == "" {
+			return nil
+		}
+
+		return &ImportTarget{FilePath: filePath}
+
+	case "import_from_statement":
+		moduleNode := node.ChildByFieldName("module_name")
+		if name == WildcardName && moduleNode != nil {
+			filePath, _ := py.findModule(moduleNode.Content(py.module.Source))
+			if filePath == "" {
+				return nil
+			}
+
+			return &ImportTarget{FilePath: filePath}
+		}
+
+		importedName := py.importedNameOf(node, name)
+		if importedName == nil || moduleNode == nil {
+			return nil
+		}
+
+		filePath, packageDir := py.findModule(moduleNode.Content(py.module.Source))
+		target := &ImportTarget{FilePath: filePath, Symbol: *importedName}
+		if packageDir != "" {
+			target.SubmodulePath, _ = findModuleFile([]string{packageDir}, *importedName)
+		}
+
+		if target.FilePath == "" && target.SubmodulePath == "" {
+			return nil
+		}
+
+		return target
+	}
+
+	return nil
+}
+
+// moduleBoundBy returns the dotted name of the module that an `import_statement`
+// binds to the local name `name`.
+// `import a.b.c` binds the top-level package `a`, while `import a.b as c`
+// binds `c` to the module `a.b`.
+func (py *Python) moduleBoundBy(node *sitter.Node, name string) *string {
+	for _, nameNode := range util.ChildrenWithFieldName(node, "name") {
+		switch nameNode.Type() {
+		case "dotted_name":
+			firstChild := nameNode.NamedChild(0)
+			if firstChild != nil && firstChild.Content(py.module.Source) == name {
+				return &name
+			}
+
+		case "aliased_import":
+			aliasNode := nameNode.ChildByFieldName("alias")
+			moduleNode := nameNode.ChildByFieldName("name")
+			if aliasNode == nil || moduleNode == nil {
+				continue
+			}
+
+			if aliasNode.Content(py.module.Source) == name {
+				moduleName := moduleNode.Content(py.module.Source)
+				return &moduleName
+			}
+		}
+	}
+
+	return nil
+}
+
+// importedNameOf returns the name of the symbol that an `import_from_statement`
+// binds to the local name `name`. (e.g: `b` for `c` in `from a import b as c`)
+func (py *Python) importedNameOf(node *sitter.Node, name string) *string {
+	for _, nameNode := range util.ChildrenWithFieldName(node, "name") {
+		switch nameNode.Type() {
+		case "dotted_name":
+			importedName := nameNode.Content(py.module.Source)
+			if importedName == name {
+				return &importedName
+			}
+
+		case "aliased_import":
+			aliasNode := nameNode.ChildByFieldName("alias")
+			importedNode := nameNode.ChildByFieldName("name")
+			if aliasNode == nil || importedNode == nil {
+				continue
+			}
+
+			if aliasNode.Content(py.module.Source) == name {
+				importedName := importedNode.Content(py.module.Source)
+				return &importedName
+			}
+		}
+	}
+
+	return nil
+}
+
+// findModule finds the file of a module from its (possibly relative) dotted name.
+// If the module is a package, `packageDir` is the directory that holds its submodules.
+func (py *Python) findModule(moduleName string) (filePath string, packageDir string) {
+	upLevel := 0
+	for strings.HasPrefix(moduleName, ".") {
+		moduleName = moduleName[1:]
+		upLevel++
+	}
+
+	// Relative imports are resolved from the importing file's package,
+	// and absolute imports are searched for in every directory of the sys.path.
+	var searchRoots []string
+	if upLevel > 0 {
+		rootPath := py.Module().FileName
+		for i := 0; i < upLevel; i++ {
+			rootPath = filepath.Dir(rootPath)
+		}
+		searchRoots = []string{rootPath}
+	} else {
+		searchRoots = py.SysPath.Roots
+	}
+
+	filePath, packageDir = findModuleFile(searchRoots, moduleName)
+	if filePath == "" && upLevel == 0 {
+		// Modules without any source (like the builtin modules of the stdlib) may have stubs
+		if stubPath, stubDir := findModuleFileWithExts(py.SysPath.StubRoots, moduleName, ".pyi"); stubPath != "" {
+			return stubPath, stubDir
+		}
+	}
+
+	return filePath, packageDir
+}
+
+// ModulesRunBy returns the files of the modules whose top-level code an import statement runs.
+// Importing a module runs every package that it is in first, so `import a.b.c` runs
+// `a/__init__.py`, `a/b/__init__.py` and `a/b/c.py`. `from a import b` also runs
+// `a/b.py` when `b` is a submodule.
+func (py *Python) ModulesRunBy(importNode *sitter.Node) []string {
+	var moduleNames []string
+	switch importNode.Type() {
+	case "import_statement":
+		for _, nameNode := range util.ChildrenWithFieldName(importNode, "name") {
+			if nameNode.Type() == "aliased_import" {
+				nameNode = nameNode.ChildByFieldName("name")
+			}
+
+			if nameNode != nil {
+				moduleNames = append(moduleNames, nameNode.Content(py.module.Source))
+			}
+		}
+	case "import_from_statement":
+		if moduleNode := importNode.ChildByFieldName("module_name"); moduleNode != nil {
+			moduleNames = append(moduleNames, moduleNode.Content(py.module.Source))
+		}
+	default:
+		return nil
+	}
+
+	var files []string
+	addFile := func(filePath string) {
+		if filePath != "" && !slices.Contains(files, filePath) {
+			files = append(files, filePath)
+		}
+	}
+
+	for _, moduleName := range moduleNames {
+		// The packages that a relative import is relative to have been imported already.
+		dots := len(moduleName) - len(strings.TrimLeft(moduleName, "."))
+		parts := strings.Split(moduleName[dots:], ".")
+		for i := range parts {
+			if parts[i] == "" {
+				continue
+			}
+
+			filePath, _ := py.findModule(moduleName[:dots] + strings.Join(parts[:i+1], "."))
+			addFile(filePath)
+		}
+	}
+
+	if importNode.Type() == "import_from_statement" {
+		for _, nameNode := range util.ChildrenWithFieldName(importNode, "name") {
+			if nameNode.Type() == "aliased_import" {
+				nameNode = nameNode.ChildByFieldName("alias")
+			}
+
+			if nameNode == nil {
+				continue
+			}
+
+			if target := py.ImportTargetOf(importNode, nameNode.Content(py.module.Source)); target != nil {
+				addFile(target.SubmodulePath)
+			}
+		}
+	}
+
+	return files
+}
+
+// findModuleFile searches for a module in `roots`, in order.
+// `moduleName` is a dotted name relative to the roots (e.g: `requests.sessions`).
+// Regular packages and modules in any root take precedence over namespace
+// packages (directories without an `__init__.py`), which have no file.
+// A stub file (`.pyi`) stands in for a module that has no python source in
+// its directory, like a C extension.
+func findModuleFile(roots []string, moduleName string) (filePath string, packageDir string) {
+	return findModuleFileWithExts(roots, moduleName, ".py", ".pyi")
+}
+
+// findModuleFileWithExts searches for a module in `roots` like `findModuleFile` does,
+// but only finds files with one of the extensions in `exts` (in order of preference).
+func findModuleFileWithExts(roots []string, moduleName string, exts ...string) (filePath string, packageDir string) {
+	modulePath := filepath.Join(strings.Split(moduleName, ".")...)
+
+	namespaceDir := ""
+	for _, root := range roots {
+		dir := filepath.Join(root, modulePath)
+		for _, ext := range exts {
+			initFile := filepath.Join(dir, "__init__"+ext)
+			if info, err := os.Stat(initFile); err == nil && !info.IsDir() {
+				return initFile, dir
+			}
+		}
+
+		if modulePath != "" {
+			for _, ext := range exts {
+				moduleFile := dir + ext
+				if info, err := os.Stat(moduleFile); err == nil && !info.IsDir() {
+					return moduleFile, ""
+				}
+			}
+		}
+
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && namespaceDir == "" {
+			namespaceDir = dir
+		}
+	}
+
+	return "", namespaceDir
+}
+
+func (py *Python) FilePathOfModule(name string) *string {
+	if name == "" || strings.HasPrefix(name, ".") {
+		return nil
+	}
+
+	filePath, _ := py.findModule(name)
+	if filePath == "" {
+		return nil
+	}
+
+	return &filePath
+}
+
+func (py *Python) FilePathOfSubmodule(name string) *string {
+	if filepath.Base(py.module.FileName) != "__init__.py" {
+		return nil
+	}
+
+	filePath, _ := findModuleFile([]string{filepath.Dir(py.module.FileName)}, name)
+	if filePath == "" {
+		return nil
+	}
+
+	return &filePath
+}
+
+func (py *Python) ParseImportedFile(filePath string) (ParsedFile, error) {
+	source, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse file: %v", err)
+	}
+
+	projectRoot, _ := findProjectRoot(filePath)
+	return parsePython(filePath, source, projectRoot, py.SysPath)
+}
+
+func (py *Python) IsDottedExpr(node *sitter.Node) bool {
+	return node.Type() == "attribute"
+}
+
+func (py *Python) GetCallee(callExpr *sitter.Node) *sitter.Node {
+	// `@cache` calls `cache` with the decorated definition
+	if py.IsDecoratorCall(callExpr) {
+		return callExpr.NamedChild(0)
+	}
+
+	return callExpr.ChildByFieldName("function")
+}
+
+func (py *Python) FunctionDefFromNode(node *sitter.Node) *sitter.Node {
+	if !py.IsClassDef(node) {
+		return nil
+	}
+
+	// Inherited constructors are resolved with the class's MRO in the call graph.
+	body := node.ChildByFieldName("body")
+	// TODO: make this a reverse iterator instead to find the last __init__
+	// because @overload's exist
+	return util.FindMatchingChild(body, func(child *sitter.Node) bool {
+		if child.Type() != "function_definition" {
+			return false
+		}
+
+		funcName := child.ChildByFieldName("name")
+		if funcName == nil {
+			return false
+		}
+
+		return funcName.Content(py.module.Source) == "__init__"
+	})
+}
+
+func (py *Python) ResolveExportedSymbol(name string) []*sitter.Node {
+	globalScope := py.Module().GlobalScope
+	return globalScope.Definitions[name]
+}
+
+// ExportsToWildcard returns `true` if `from <this module> import *` binds `name`.
+// When the module defines `__all__`, only the names listed in it are exported.
+// Otherwise, every name that doesn't start with an underscore is.
+func (py *Python) ExportsToWildcard(name string) bool {
+	allNames := py.dunderAll()
+	if allNames == nil {
+		return !strings.HasPrefix(name, "_")
+	}
+
+	return slices.Contains(allNames, name)
+}
+
+// dunderAll returns the names listed in the module's `__all__`, or nil if the module
+// doesn't define `__all__` as a list or tuple of string literals. The names of every
+// definition are included, along with the ones that are added by `__all__ += [...]`.
+func (py *Python) dunderAll() []string {
+	var names []string
+	for _, def := range py.module.GlobalScope.Definitions["__all__"] {
+		allExpr := def
+		if parent := def.Parent(); parent != nil && parent.Type() == "augmented_assignment" {
+			operator := parent.ChildByFieldName("operator")
+			if operator == nil || operator.Type() != "+=" {
+				continue
+			}
+			allExpr = parent.ChildByFieldName("right")
+		}
+
+		if allExpr == nil || (allExpr.Type() != "list" && allExpr.Type() != "tuple") {
+			continue
+		}
+
+		if names == nil {
+			names = []string{}
+		}
+
+		for i := 0; i < int(allExpr.NamedChildCount()); i++ {
+			if name := py.stringLiteralValue(allExpr.NamedChild(i)); name != nil {
+				names = append(names, *name)
+			}
+		}
+	}
+
+	return names
+}
+
+// stringLiteralValue returns the value of a plain string literal, like `"foo"`.
+// Returns nil for any other node, including f-strings with interpolations.
+func (py *Python) stringLiteralValue(node *sitter.Node) *string {
+	if node == nil || node.Type() != "string" {
+		return nil
+	}
+
+	value := ""
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+		switch child.Type() {
+		case "string_content":
+			value += child.Content(py.module.Source)
+		case "string_start", "string_end":
+			continue
+		default:
+			return nil
+		}
+	}
+
+	return &value
+}
+
+// StringValueOf returns the value of a string literal (including implicitly concatenated literals,
+// like `"a" "b"`), or of the module attributes `__name__` and `__package__`.
+func (py *Python) StringValueOf(node *sitter.Node) *string {
+	switch node.Type() {
+	case "string":
+		return py.stringLiteralValue(node)
+
+	case "concatenated_string":
+		value := ""
+		for _, part := range namedChildrenExceptComments(node) {
+			partValue := py.stringLiteralValue(part)
+			if partValue == nil {
+				return nil
+			}
+			value += *partValue
+		}
+		return &value
+
+	case "identifier":
+		moduleName := py.ModuleName()
+		if moduleName == nil {
+			return nil
+		}
+
+		switch node.Content(py.module.Source) {
+		case "__name__":
+			return moduleName
+		case "__package__":
+			// The package of a module is the module itself for packages
+			// (`__init__` files), and its parent package for other modules.
+			if filepath.Base(strings.TrimSuffix(py.module.FileName, filepath.Ext(py.module.FileName))) == "__init__" {
+				return moduleName
+			}
+
+			packageName := ""
+			if dot := strings.LastIndex(*moduleName, "."); dot >= 0 {
+				packageName = (*moduleName)[:dot]
+			}
+			return &packageName
+		}
+	}
+
+	return nil
+}
+
+// DynamicLookupOf returns the module or attribute that a call to one of these looks up:
+//   - `importlib.import_module(name, package)`
+//   - `__import__(name, globals, locals, fromlist)`, which evaluates to the top-level
+//     package of the module, unless `fromlist` is given.
+//   - `getattr(object, name, default)`
+//
+// The callee is matched by its name, so these builtins are assumed not to be shadowed.
+func (py *Python) DynamicLookupOf(node *sitter.Node) *DynamicLookup {
+	if !py.IsCallExpr(node) {
+		return nil
+	}
+
+	callee := py.GetCallee(node)
+	if callee == nil {
+		return nil
+	}
+
+	args := py.ArgumentsOf(node)
+	argument := func(position int, keyword string) *sitter.Node {
+		for i, arg := range args {
+			if arg.IsSplat {
+				return nil
+			}
+
+			if (arg.Keyword == "" && i == position) || (keyword != "" && arg.Keyword == keyword) {
+				return arg.Value
+			}
+		}
+		return nil
+	}
+
+	switch dottedNameOf(callee, py.module.Source) {
+	case "importlib.import_module", "import_module":
+		if name := argument(0, "name"); name != nil {
+			return &DynamicLookup{Name: name, Package: argument(1, "package")}
+		}
+
+	case "__import__":
+		if name := argument(0, "name"); name != nil {
+			fromlist := argument(3, "fromlist")
+			return &DynamicLookup{Name: name, TopLevel: fromlist == nil || fromlist.Type() == "none"}
+		}
+
+	case "getattr":
+		object, name := argument(0, ""), argument(1, "")
+		if object != nil && name != nil {
+			return &DynamicLookup{Object: object, Name: name, Default: argument(2, "")}
+		}
+	}
+
+	return nil
+}
+
+func (py *Python) GetObjectAndProperty(node *sitter.Node) (*sitter.Node, *sitter.Node) {
+	return node.ChildByFieldName("object"), node.ChildByFieldName("attribute")
+}
+
+func (py *Python) ModuleName() *string {
+	importRoot := py.SysPath.RootOf(py.module.FileName)
+	if importRoot == nil {
+		return nil
+	}
+
+	relPath, err := filepath.Rel(*importRoot, py.module.FileName)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return nil
+	}
+
+	relPath = strings.TrimSuffix(relPath, filepath.Ext(relPath))
+	parts := strings.Split(relPath, string(filepath.Separator))
+	if len(parts) > 1 && parts[len(parts)-1] == "__init__" {
+		parts = parts[:len(parts)-1]
+	}
+
+	// The stubs in `requests-stubs/` describe the package `requests`
+	if py.isStub() {
+		parts[0] = strings.TrimSuffix(parts[0], "-stubs")
+	}
+
+	moduleName := strings.Join(parts, ".")
+	return &moduleName
+}
+
+// isStub returns `true` if this file is a stub file, which only has type declarations.
+func (py *Python) isStub() bool {
+	return filepath.Ext(py.module.FileName) == ".pyi"
+}
+
+// StubFilePath returns the stub file that describes this module: a `.pyi` file next to it,
+// or one found by `SysPath.FindStubFile`. Returns nil for stub files, and for modules without stubs.
+func (py *Python) StubFilePath() *string {
+	if py.isStub() {
+		return nil
+	}
+
+	stubPath := strings.TrimSuffix(py.module.FileName, ".py") + ".pyi"
+	if info, err := os.Stat(stubPath); err == nil && !info.IsDir() {
+		return &stubPath
+	}
+
+	moduleName := py.ModuleName()
+	if moduleName == nil {
+		return nil
+	}
+
+	stubPath = py.SysPath.FindStubFile(*moduleName)
+	if stubPath == "" {
+		return nil
+	}
+
+	return &stubPath
+}
+
+// ImplementationFilePath returns the python source of the module that this stub file describes.
+// Returns nil if this file isn't a stub, or if the module has no python source (e.g: C extensions).
+func (py *Python) ImplementationFilePath() *string {
+	if !py.isStub() {
+		return nil
+	}
+
+	sourcePath := strings.TrimSuffix(py.module.FileName, ".pyi") + ".py"
+	if info, err := os.Stat(sourcePath); err == nil && !info.IsDir() {
+		return &sourcePath
+	}
+
+	moduleName := py.ModuleName()
+	if moduleName == nil {
+		return nil
+	}
+
+	sourcePath, _ = findModuleFileWithExts(py.SysPath.Roots, *moduleName, ".py")
+	if sourcePath == "" {
+		return nil
+	}
+
+	return &sourcePath
+}
+
+func (py *Python) QualifiedNameOf(node *sitter.Node) *string {
+	var name *string
+	if py.IsClassDef(node) {
+		if nameNode := node.ChildByFieldName("name"); nameNode != nil {
+			className := nameNode.Content(py.module.Source)
+			name = &className
+		}
+	} else {
+		name = py.NameOfFunction(node)
+	}
+
+	if name == nil || *name == "" {
+		return nil
+	}
+
+	names := []string{*name}
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.Type() != "class_definition" && parent.Type() != "function_definition" {
+			continue
+		}
+
+		parentName := parent.ChildByFieldName("name")
+		if parentName == nil {
+			return nil
+		}
+
+		names = append([]string{parentName.Content(py.module.Source)}, names...)
+	}
+
+	qualifiedName := strings.Join(names, ".")
+	return &qualifiedName
+}
+
+// distribution returns the installed distribution that this file belongs
+// to, or nil if the file is not inside a site-packages directory.
+func (py *Python) distribution() *Distribution {
+	sitePackagesDir := sitePackagesDirOf(py.module.FileName)
+	if sitePackagesDir == nil {
+		return nil
+	}
+
+	return py.SysPath.SitePackagesAt(*sitePackagesDir).DistributionOfFile(py.module.FileName)
+}
+
+func (py *Python) PackageName() *string {
+	if dist := py.distribution(); dist != nil {
+		return &dist.Name
+	}
+
+	if py.module.ProjectRoot == nil {
+		return nil
+	}
+
+	baseName := filepath.Base(*py.module.ProjectRoot)
+	return &baseName
+}
+
+func (py *Python) PackageVersion() *string {
+	dist := py.distribution()
+	if dist == nil {
+		return nil
+	}
+
+	return &dist.Version
 }
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/srijanpaul-deepsource/reachable/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,3 +43,219 @@ func Test_FilePathForImport(t *testing.T) {
 	require.NotNil(t, py)
 
 }
+
+func Test_ModuleName(t *testing.T) {
+	goProjectRoot := goProjectRoot()
+	utilDotPy := filepath.Join(goProjectRoot, "test-projects/pyproject/src/mypackage/util.py")
+
+	contents, err := os.ReadFile(utilDotPy)
+	require.NoError(t, err)
+
+	py, err := ParsePython(utilDotPy, contents)
+	require.NoError(t, err)
+
+	moduleName := py.ModuleName()
+	require.NotNil(t, moduleName)
+	assert.Equal(t, "mypackage.util", *moduleName)
+}
+
+func Test_QualifiedNameOf(t *testing.T) {
+	code := `
+class Session:
+	def request(self):
+		def inner():
+			pass
+
+def get():
+	pass
+`
+	py, err := ParsePython("test.py", []byte(code))
+	require.NoError(t, err)
+
+	session := py.Module().GlobalScope.Symbols["Session"]
+	require.NotNil(t, session)
+	request := py.Module().ScopeOfNode[session].Symbols["request"]
+	require.NotNil(t, request)
+	inner := py.Module().ScopeOfNode[request].Symbols["inner"]
+	require.NotNil(t, inner)
+	get := py.Module().GlobalScope.Symbols["get"]
+	require.NotNil(t, get)
+
+	assert.Equal(t, "Session.request", *py.QualifiedNameOf(request))
+	assert.Equal(t, "Session.request.inner", *py.QualifiedNameOf(inner))
+	assert.Equal(t, "get", *py.QualifiedNameOf(get))
+}
+
+func Test_ImportTargetFromVenv(t *testing.T) {
+	projectRoot := t.TempDir()
+	sitePackages := filepath.Join(projectRoot, ".venv", "lib", "python3.12", "site-packages")
+	testutil.WriteFiles(t, projectRoot, map[string]string{
+		"setup.py":                 "",
+		"src/app/__init__.py":      "",
+		"src/app/main.py":          "import requests\nimport app.util as util\nimport editable\nimport extra\n",
+		"src/app/util.py":          "",
+		".venv/pyvenv.cfg":         "home = /nonexistent/bin\nversion = 3.12.1\n",
+		"vendor/extra.py":          "",
+		"editable/src/editable.py": "",
+	})
+	testutil.WriteFiles(t, sitePackages, map[string]string{
+		"requests/__init__.py": "",
+		"__editable__.pth":     "import sys\n# comment\n" + filepath.Join(projectRoot, "editable", "src") + "\n",
+	})
+
+	mainDotPy := filepath.Join(projectRoot, "src", "app", "main.py")
+	contents, err := os.ReadFile(mainDotPy)
+	require.NoError(t, err)
+
+	py, err := ParsePythonWithEnv(mainDotPy, contents, PythonEnv{ExtraPaths: []string{"vendor"}})
+	require.NoError(t, err)
+
+	want := []struct {
+		name     string
+		filePath string
+	}{
+		{"requests", filepath.Join(sitePackages, "requests", "__init__.py")},
+		{"util", filepath.Join(projectRoot, "src", "app", "util.py")},
+		{"editable", filepath.Join(projectRoot, "editable", "src", "editable.py")},
+		{"extra", filepath.Join(projectRoot, "vendor", "extra.py")},
+	}
+
+	ast := py.Module().Ast
+	require.Equal(t, len(want), int(ast.NamedChildCount()))
+	for i, w := range want {
+		got := py.ImportTargetOf(ast.NamedChild(i), w.name)
+		require.NotNil(t, got, w.filePath)
+		assert.Equal(t, w.filePath, got.FilePath)
+	}
+}
+
+func Test_ImportTargetOfEveryName(t *testing.T) {
+	projectRoot := t.TempDir()
+	testutil.WriteFiles(t, projectRoot, map[string]string{
+		"setup.py":              "",
+		"pkg/__init__.py":       "def func():\n\tpass\n",
+		"pkg/sub_a.py":          "",
+		"pkg/sub_b/__init__.py": "",
+		"main.py":               "from pkg import sub_a, sub_b as b, func as f\n",
+	})
+
+	mainDotPy := filepath.Join(projectRoot, "main.py")
+	contents, err := os.ReadFile(mainDotPy)
+	require.NoError(t, err)
+
+	py, err := ParsePython(mainDotPy, contents)
+	require.NoError(t, err)
+
+	importStmt := py.Module().Ast.NamedChild(0)
+	initDotPy := filepath.Join(projectRoot, "pkg", "__init__.py")
+	want := map[string]ImportTarget{
+		"sub_a": {
+			FilePath:      initDotPy,
+			Symbol:        "sub_a",
+			SubmodulePath: filepath.Join(projectRoot, "pkg", "sub_a.py"),
+		},
+		"b": {
+			FilePath:      initDotPy,
+			Symbol:        "sub_b",
+			SubmodulePath: filepath.Join(projectRoot, "pkg", "sub_b", "__init__.py"),
+		},
+		"f": {FilePath: initDotPy, Symbol: "func"},
+	}
+
+	for name, wantTarget := range want {
+		got := py.ImportTargetOf(importStmt, name)
+		require.NotNil(t, got, name)
+		assert.Equal(t, wantTarget, *got, name)
+	}
+
+	assert.Nil(t, py.ImportTargetOf(importStmt, "sub_b"))
+}
+
+func Test_AnnotatedTypesOf(t *testing.T) {
+	source := []byte(`
+def f(
+	a: httpx.Client,
+	b: Optional[Session],
+	c: "Session",
+	d: Session | None,
+	e: typing.Union[A, "B"],
+	f: "Optional[lib.Session]",
+	g: list[int],
+	h: Type[Session],
+	i: Annotated[Session, "meta"],
+	*args: Session,
+): pass
+`)
+
+	py, err := ParsePython("test.py", source)
+	require.NoError(t, err)
+
+	fn := py.module.GlobalScope.Symbols["f"]
+	require.NotNil(t, fn)
+
+	want := map[string][]string{
+		"a": {"httpx.Client"},
+		"b": {"Session"},
+		"c": {"Session"},
+		"d": {"Session"},
+		"e": {"A", "B"},
+		"f": {"lib.Session"},
+		"g": {"list"},
+		"h": nil,
+		"i": {"Session"},
+	}
+
+	params := py.ParametersOf(fn)
+	require.Len(t, params, len(want)+1)
+	for _, param := range params {
+		annotation := py.TypeAnnotationOf(param.Node)
+		if param.IsVariadic {
+			assert.Nil(t, annotation)
+			continue
+		}
+
+		require.NotNil(t, annotation, param.Name)
+		assert.Equal(t, want[param.Name], py.AnnotatedTypesOf(annotation), param.Name)
+	}
+}
+
+func Test_StubFilePath(t *testing.T) {
+	sitePackages := filepath.Join(t.TempDir(), "venv", "lib", "python3.12", "site-packages")
+	testutil.WriteFiles(t, sitePackages, map[string]string{
+		"requests/__init__.py":        "",
+		"requests/sessions.py":        "class Session:\n\tpass\n",
+		"requests-stubs/__init__.pyi": "",
+		"requests-stubs/sessions.pyi": "class Session: ...\n",
+		"yaml/__init__.py":            "def load(stream):\n\tpass\n",
+		"yaml/__init__.pyi":           "def load(stream) -> object: ...\n",
+	})
+
+	parse := func(relPath string) *Python {
+		filePath := filepath.Join(sitePackages, relPath)
+		contents, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+
+		py, err := ParsePython(filePath, contents)
+		require.NoError(t, err)
+		return py
+	}
+
+	cases := map[string]string{
+		"requests/sessions.py": "requests-stubs/sessions.pyi",
+		"yaml/__init__.py":     "yaml/__init__.pyi",
+	}
+
+	for sourcePath, stubPath := range cases {
+		stubFilePath := parse(sourcePath).StubFilePath()
+		require.NotNil(t, stubFilePath, sourcePath)
+		assert.Equal(t, filepath.Join(sitePackages, stubPath), *stubFilePath)
+
+		stub := parse(stubPath)
+		assert.Nil(t, stub.StubFilePath())
+		assert.Equal(t, parse(sourcePath).ModuleName(), stub.ModuleName())
+
+		sourceFilePath := stub.ImplementationFilePath()
+		require.NotNil(t, sourceFilePath, stubPath)
+		assert.Equal(t, filepath.Join(sitePackages, sourcePath), *sourceFilePath)
+	}
+}
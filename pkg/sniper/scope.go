@@ -16,8 +16,17 @@ var ScopeNodeTypes = []string{
 	"class_declaration",
 	"method_definition",
 	"function_declaration",
+	"lambda",
+	"list_comprehension",
+	"set_comprehension",
+	"dictionary_comprehension",
+	"generator_expression",
 }
 
+// WildcardName is the name of declarations made by wildcard imports
+// (e.g: `from x import *` in python), which bind every public name of a module.
+const WildcardName = "*"
+
 // ScopeOfNode maps a tree-sitter node (like
 // function def or block node) to the scope
 // that the node introduces into the program.
@@ -40,13 +49,33 @@ type Scope struct {
 	Parent *Scope
 	// Children is a list of sub-scopes
 	Children []*Scope
-	// Symbols maps a name to an AST node that
+	// Symbols maps a name to the first AST node that
 	// the name was initialized to
 	Symbols map[string]*sitter.Node
+	// Definitions maps a name to every AST node that the name is initialized to,
+	// in source order. A name can be bound more than once (e.g: in both branches
+	// of an `if`), and any one of these definitions may reach a use of the name.
+	Definitions map[string][]*sitter.Node
 	// Name is the inverse-map of `Symbols`.
 	NameOfNode map[*sitter.Node]string
 	// (TODO)
 	FilePathOfImport map[*sitter.Node]string
+	// WildcardImports are the wildcard import statements in this scope, in source order.
+	// Names that aren't found in `Symbols` may be bound by one of these.
+	WildcardImports []*sitter.Node
+	// GlobalNames are the names that this scope declares to be bound in the global
+	// scope (e.g: with `global x` in python). Their definitions are moved there.
+	GlobalNames map[string]struct{}
+	// NonlocalNames are the names that this scope declares to be bound in the nearest
+	// enclosing function scope that binds them (e.g: with `nonlocal x` in python).
+	// Their definitions are moved to that scope.
+	NonlocalNames map[string]struct{}
+	// IsClass is `true` for the scope of a class body. Names defined in a class body
+	// aren't visible in the scopes nested in it, like the bodies of its methods.
+	IsClass bool
+	// IsComprehension is `true` for the scope of a comprehension. Assignment expressions
+	// in a comprehension bind their names in the nearest enclosing scope that isn't one.
+	IsComprehension bool
 	// AstNode is the node that introduced this scope
 	// in the program
 	AstNode *sitter.Node
@@ -84,12 +113,66 @@ func (s *Scope) toDotNode(g *dot.Graph) dot.Node {
 
 // Lookup finds a symbol starting from the current scope and going up
 func (s *Scope) Lookup(name string) *sitter.Node {
-	if s.Symbols[name] != nil {
-		return s.Symbols[name]
+	defs := s.LookupAll(name)
+	if len(defs) == 0 {
+		return nil
 	}
 
-	if s.Parent != nil {
-		return s.Parent.Lookup(name)
+	return defs[0]
+}
+
+// LookupAll finds every definition of a symbol in the nearest scope
+// that defines it, starting from the current scope and going up.
+// Enclosing class scopes are skipped, and names declared global
+// are looked up in the global scope.
+func (s *Scope) LookupAll(name string) []*sitter.Node {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if scope.IsClass && scope != s {
+			continue
+		}
+
+		if _, isGlobal := scope.GlobalNames[name]; isGlobal {
+			return scope.root().Definitions[name]
+		}
+
+		if defs := scope.Definitions[name]; len(defs) > 0 {
+			return defs
+		}
+	}
+
+	return nil
+}
+
+// root returns the global scope that this scope is nested in.
+func (s *Scope) root() *Scope {
+	for s.Parent != nil {
+		s = s.Parent
+	}
+
+	return s
+}
+
+// enclosingBindingOf returns the nearest enclosing function scope that binds `name`,
+// which a `nonlocal` declaration of `name` in this scope refers to.
+// The global scope is never returned, since `nonlocal` names can't be global.
+func (s *Scope) enclosingBindingOf(name string) *Scope {
+	for scope := s.Parent; scope != nil && scope.Parent != nil; scope = scope.Parent {
+		if scope.IsClass {
+			continue
+		}
+
+		// A `nonlocal` declaration in the enclosing scope refers to an even outer scope.
+		if _, isNonlocal := scope.NonlocalNames[name]; isNonlocal {
+			continue
+		}
+
+		if _, isGlobal := scope.GlobalNames[name]; isGlobal {
+			return nil
+		}
+
+		if len(scope.Definitions[name]) > 0 {
+			return scope
+		}
 	}
 
 	return nil
@@ -100,9 +183,63 @@ func (s *Scope) Lookup(name string) *sitter.Node {
 func makeLexicalScopeTree(lang ParsedFile, root *sitter.Node) (*Scope, ScopeOfNode) {
 	scopeOfNode := make(ScopeOfNode)
 	globalScope := makeLexicalScopeTree_(lang, root, nil, scopeOfNode)
+	bindDeclaredNames(globalScope)
 	return globalScope, scopeOfNode
 }
 
+// bindDeclaredNames moves the definitions of names that are declared `global` or `nonlocal`
+// in a scope to the scope that they are bound in. The scopes are found before any definitions
+// are moved, since a scope binds a name if it defines the name itself.
+func bindDeclaredNames(globalScope *Scope) {
+	type binding struct {
+		from, to *Scope
+		name     string
+	}
+
+	var bindings []binding
+	var visit func(scope *Scope)
+	visit = func(scope *Scope) {
+		for name := range scope.GlobalNames {
+			bindings = append(bindings, binding{scope, globalScope, name})
+		}
+
+		for name := range scope.NonlocalNames {
+			if to := scope.enclosingBindingOf(name); to != nil {
+				bindings = append(bindings, binding{scope, to, name})
+			}
+		}
+
+		for _, child := range scope.Children {
+			visit(child)
+		}
+	}
+
+	visit(globalScope)
+
+	for _, b := range bindings {
+		defs := b.from.Definitions[b.name]
+		if len(defs) == 0 || b.from == b.to {
+			continue
+		}
+
+		delete(b.from.Definitions, b.name)
+		delete(b.from.Symbols, b.name)
+
+		// Definitions are kept in source order
+		toDefs := append(b.to.Definitions[b.name], defs...)
+		slices.SortStableFunc(toDefs, func(a, b *sitter.Node) int {
+			return int(a.StartByte()) - int(b.StartByte())
+		})
+
+		b.to.Definitions[b.name] = toDefs
+		b.to.Symbols[b.name] = toDefs[0]
+		for _, def := range defs {
+			delete(b.from.NameOfNode, def)
+			b.to.NameOfNode[def] = b.name
+		}
+	}
+}
+
 // makeLexicalScopeTree_ is the recursive helper
 // for makeLexicalScopeTree
 // It traverses the AST top-down,
@@ -121,15 +258,52 @@ func makeLexicalScopeTree_(
 	nodeType := node.Type()
 	isBlockNode := slices.Contains(ScopeNodeTypes, nodeType)
 
+	// Parts of a block (like the bases of a class) are evaluated in the scope that
+	// encloses it, so the scopes of the nodes inside them are children of that scope.
+	if scope != nil && scope.Parent != nil && lang.IsEvaluatedInEnclosingScope(node) {
+		scope = scope.Parent
+		scopeOfNode[node] = scope
+	}
+
+	// An assignment expression in a comprehension binds its name
+	// in the scope that contains the comprehension (PEP 572).
+	declScope := scope
+	if lang.IsAssignmentExpr(node) {
+		for declScope.IsComprehension && declScope.Parent != nil {
+			declScope = declScope.Parent
+		}
+	}
+
+	for _, name := range lang.GlobalNamesOf(node) {
+		scope.GlobalNames[name] = struct{}{}
+	}
+
+	for _, name := range lang.NonlocalNamesOf(node) {
+		scope.NonlocalNames[name] = struct{}{}
+	}
+
 	decls := lang.GetDecls(node)
 	for _, decl := range decls {
 		writeExpr, name := decl.InitExpr, decl.Name
-		if writeExpr != nil && scope.Symbols[name] == nil {
+		if name == WildcardName {
+			if writeExpr != nil {
+				scope.WildcardImports = append(scope.WildcardImports, writeExpr)
+			}
+			continue
+		}
+
+		if writeExpr == nil {
+			continue
+		}
+
+		if declScope.Symbols[name] == nil {
 			// add a new variable declaration to the scope
 			// if it doesn't exist already
-			scope.Symbols[name] = writeExpr
-			scope.NameOfNode[writeExpr] = name
+			declScope.Symbols[name] = writeExpr
 		}
+
+		declScope.Definitions[name] = append(declScope.Definitions[name], writeExpr)
+		declScope.NameOfNode[writeExpr] = name
 	}
 
 	nextScope := scope
@@ -138,8 +312,13 @@ func makeLexicalScopeTree_(
 			AstNode:          node,
 			Parent:           scope,
 			Symbols:          make(map[string]*sitter.Node),
+			Definitions:      make(map[string][]*sitter.Node),
 			FilePathOfImport: make(map[*sitter.Node]string),
 			NameOfNode:       make(map[*sitter.Node]string),
+			GlobalNames:      make(map[string]struct{}),
+			NonlocalNames:    make(map[string]struct{}),
+			IsClass:          lang.IsClassDef(node),
+			IsComprehension:  lang.IsComprehension(node),
 		}
 
 		scopeOfNode[node] = nextScope
@@ -158,28 +337,15 @@ func makeLexicalScopeTree_(
 	return scope
 }
 
-// findNearestBlockNode finds the nearest surrounding block node for any AST node
-func findNearestBlockNode(node *sitter.Node) *sitter.Node {
-	for node != nil {
-		if slices.Contains(ScopeNodeTypes, node.Type()) {
-			return node
-		}
-		node = node.Parent()
-	}
-	return nil
-}
-
-// GetScope finds the nearest surrounding scope of a node
+// GetScope finds the nearest surrounding scope of a node. Parts of a block node that are
+// evaluated in the enclosing scope (see `ParsedFile.IsEvaluatedInEnclosingScope`) are
+// mapped to that scope, and so are the nodes inside them.
 func GetScope(module *Module, node *sitter.Node) *Scope {
-	nearestBlock := findNearestBlockNode(node)
-	if nearestBlock == nil {
-		return nil
-	}
-
-	nearestScope, exists := module.ScopeOfNode[nearestBlock]
-	if !exists {
-		return nil
+	for ; node != nil; node = node.Parent() {
+		if scope, exists := module.ScopeOfNode[node]; exists {
+			return scope
+		}
 	}
 
-	return nearestScope
+	return nil
 }
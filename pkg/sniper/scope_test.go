@@ -3,6 +3,7 @@ package sniper
 import (
 	"testing"
 
+	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -65,3 +66,272 @@ func Test_Scope(t *testing.T) {
 	require.Contains(t, child.Symbols, "baz")
 	assert.Equal(t, "420", child.Symbols["baz"].Content(pyBytes))
 }
+
+func Test_ScopeImports(t *testing.T) {
+	code := `
+import os, urllib3.util
+import xml.etree.ElementTree as ET
+from lib import *
+`
+
+	py, err := ParsePython("test.py", []byte(code))
+	require.NoError(t, err)
+
+	scope := py.Module().GlobalScope
+	assert.Contains(t, scope.Symbols, "os")
+	assert.Contains(t, scope.Symbols, "urllib3")
+	assert.Contains(t, scope.Symbols, "ET")
+	assert.Len(t, scope.WildcardImports, 1)
+}
+
+func Test_ScopeDefinitions(t *testing.T) {
+	source := []byte(`
+try:
+	import ujson as json
+except ImportError:
+	import json
+
+def f():
+	x = 1
+	x = 2
+`)
+
+	py, err := ParsePython("test.py", source)
+	require.NoError(t, err)
+
+	scope := py.module.GlobalScope
+	require.Len(t, scope.Definitions["json"], 2)
+	assert.Equal(t, "import ujson as json", scope.Definitions["json"][0].Content(source))
+	assert.Equal(t, "import json", scope.Definitions["json"][1].Content(source))
+	// `Symbols` holds the first definition
+	assert.Equal(t, scope.Definitions["json"][0], scope.Symbols["json"])
+
+	require.Len(t, scope.Children, 1)
+	fScope := scope.Children[0]
+	require.Len(t, fScope.LookupAll("x"), 2)
+	assert.Equal(t, "2", fScope.LookupAll("x")[1].Content(source))
+	assert.Len(t, fScope.LookupAll("json"), 2)
+	assert.Empty(t, fScope.LookupAll("y"))
+}
+
+func Test_ScopeNameResolution(t *testing.T) {
+	source := []byte(`
+handler = "global"
+counter = 0
+
+class Foo:
+	handler = "class"
+
+	def method(self):
+		return handler
+
+def configure():
+	global handler
+	handler = "configured"
+
+def outer():
+	total = 0
+	def inner():
+		nonlocal total
+		total = 1
+	return inner
+
+square = lambda counter: counter * counter
+evens = [counter for counter in range(10)]
+`)
+
+	py, err := ParsePython("test.py", source)
+	require.NoError(t, err)
+
+	module := py.module
+	global := module.GlobalScope
+
+	// `handler = "configured"` is bound in the global scope
+	handlers := global.Definitions["handler"]
+	require.Len(t, handlers, 2)
+	assert.Equal(t, `"global"`, handlers[0].Content(source))
+	assert.Equal(t, `"configured"`, handlers[1].Content(source))
+
+	// Methods don't see the names defined in their class body
+	foo := global.Symbols["Foo"]
+	method := module.ScopeOfNode[foo].Symbols["method"]
+	require.NotNil(t, method)
+	assert.Equal(t, handlers, module.ScopeOfNode[method].LookupAll("handler"))
+	assert.Equal(t, `"class"`, module.ScopeOfNode[foo].Lookup("handler").Content(source))
+
+	// `total = 1` is bound in `outer`
+	outer := global.Symbols["outer"]
+	inner := module.ScopeOfNode[outer].Symbols["inner"]
+	require.NotNil(t, inner)
+	assert.Len(t, module.ScopeOfNode[outer].Definitions["total"], 2)
+	assert.NotContains(t, module.ScopeOfNode[inner].Definitions, "total")
+
+	// Lambdas and comprehensions bind their variables in their own scopes
+	assert.Len(t, global.Definitions["counter"], 1)
+	square := global.Symbols["square"]
+	require.NotNil(t, square)
+	require.Contains(t, module.ScopeOfNode[square].Symbols, "counter")
+	assert.Equal(t, "square", *py.NameOfFunction(square))
+
+	evens := global.Symbols["evens"]
+	require.NotNil(t, evens)
+	assert.Contains(t, module.ScopeOfNode[evens].Symbols, "counter")
+}
+
+func Test_ScopeBindingForms(t *testing.T) {
+	source := []byte(`
+first = second = make
+(a, (b, c)), d = (f, (g, h)), i
+head, *middle, tail = 1, 2, 3, 4
+[p, q] = pair()
+client: Client = Client()
+counter: int
+counter += 1
+with open("f") as fp, lock:
+	pass
+for handler in handlers:
+	pass
+if (match := pattern.match(text)):
+	pass
+try:
+	pass
+except ValueError as err:
+	pass
+match command:
+	case whole:
+		pass
+	case Point(x=px, y=0) | [px, *others]:
+		pass
+	case {"key": value, **extra}:
+		pass
+	case Color.RED | _:
+		pass
+	case [1, 2] as pair:
+		pass
+def run(job, retries=3, *args, timeout: int = 10, **kwargs):
+	pass
+`)
+
+	py, err := ParsePython("test.py", source)
+	require.NoError(t, err)
+
+	scope := py.module.GlobalScope
+	valueOf := func(name string) string {
+		require.Contains(t, scope.Symbols, name)
+		return scope.Symbols[name].Content(source)
+	}
+
+	// chained and nested assignments
+	assert.Equal(t, "make", valueOf("first"))
+	assert.Equal(t, "make", valueOf("second"))
+	assert.Equal(t, "f", valueOf("a"))
+	assert.Equal(t, "g", valueOf("b"))
+	assert.Equal(t, "h", valueOf("c"))
+	assert.Equal(t, "i", valueOf("d"))
+
+	// starred targets
+	assert.Equal(t, "1", valueOf("head"))
+	assert.Equal(t, "4", valueOf("tail"))
+	assert.Equal(t, "list_splat_pattern", scope.Symbols["middle"].Parent().Type())
+
+	// targets whose values aren't known are bound to themselves
+	assert.Equal(t, "p", valueOf("p"))
+	assert.Equal(t, "q", valueOf("q"))
+
+	// annotated and augmented assignments
+	assert.Equal(t, "Client()", valueOf("client"))
+	require.Len(t, scope.Definitions["client"], 2)
+	assert.Equal(t, "Client", scope.Definitions["client"][1].Content(source))
+	require.Len(t, scope.Definitions["counter"], 2)
+	assert.Equal(t, "type", scope.Definitions["counter"][0].Type())
+	assert.Equal(t, "augmented_assignment", scope.Definitions["counter"][1].Parent().Type())
+
+	// with, for, walrus and except
+	assert.Equal(t, `open("f")`, valueOf("fp"))
+	assert.NotContains(t, scope.Symbols, "lock")
+	assert.Equal(t, "for_statement", scope.Symbols["handler"].Parent().Type())
+	assert.Equal(t, "pattern.match(text)", valueOf("match"))
+	assert.Equal(t, "err", valueOf("err"))
+
+	// match/case captures
+	assert.Equal(t, "command", valueOf("whole"))
+	assert.Len(t, scope.Definitions["px"], 2)
+	assert.Contains(t, scope.Symbols, "others")
+	assert.Contains(t, scope.Symbols, "value")
+	assert.Contains(t, scope.Symbols, "extra")
+	assert.Contains(t, scope.Symbols, "pair")
+	assert.NotContains(t, scope.Symbols, "Point")
+	assert.NotContains(t, scope.Symbols, "x")
+	assert.NotContains(t, scope.Symbols, "key")
+	assert.NotContains(t, scope.Symbols, "Color")
+	assert.NotContains(t, scope.Symbols, "_")
+
+	// parameters, with and without default values
+	run := py.module.ScopeOfNode[scope.Symbols["run"]]
+	for _, param := range []string{"job", "retries", "args", "timeout", "kwargs"} {
+		assert.Contains(t, run.Symbols, param)
+	}
+}
+
+func Test_ScopeOfEnclosingParts(t *testing.T) {
+	source := []byte(`
+Base = object
+default = 1
+
+class C(Base):
+	Base = None
+
+def run(retries=default, *, timeout: Base = default) -> Base:
+	default = 2
+	Base = None
+
+xs = [1, 2]
+ys = [y := run(x) for x in xs]
+zs = [xs for xs in xs]
+`)
+
+	py, err := ParsePython("test.py", source)
+	require.NoError(t, err)
+
+	module := py.module
+	global := module.GlobalScope
+	lookup := func(node *sitter.Node) string {
+		require.NotNil(t, node)
+		def := GetScope(module, node).Lookup(node.Content(source))
+		require.NotNil(t, def)
+		return def.Content(source)
+	}
+
+	// The bases of a class are looked up outside of its body
+	class := global.Symbols["C"]
+	require.NotNil(t, class)
+	assert.Equal(t, "object", lookup(class.ChildByFieldName("superclasses").NamedChild(0)))
+	assert.Equal(t, "None", module.ScopeOfNode[class].Lookup("Base").Content(source))
+
+	// So are the default values and annotations of parameters, and the return type
+	run := global.Symbols["run"]
+	require.NotNil(t, run)
+	params := run.ChildByFieldName("parameters")
+	assert.Equal(t, "1", lookup(params.NamedChild(0).ChildByFieldName("value")))
+	assert.Equal(t, "object", lookup(params.NamedChild(2).ChildByFieldName("type").NamedChild(0)))
+	assert.Equal(t, "1", lookup(params.NamedChild(2).ChildByFieldName("value")))
+	assert.Equal(t, "object", lookup(run.ChildByFieldName("return_type").NamedChild(0)))
+	assert.Equal(t, "2", module.ScopeOfNode[run].Lookup("default").Content(source))
+
+	// An assignment expression in a comprehension binds its name outside of it,
+	// while its value is still evaluated in the comprehension's scope
+	ys := global.Symbols["ys"]
+	require.NotNil(t, ys)
+	require.Contains(t, global.Symbols, "y")
+	assert.Equal(t, "run(x)", global.Symbols["y"].Content(source))
+	assert.NotContains(t, module.ScopeOfNode[ys].Symbols, "y")
+	assert.Same(t, module.ScopeOfNode[ys], GetScope(module, global.Symbols["y"]))
+
+	// The iterable of the first `for` clause is evaluated outside of the comprehension
+	zs := global.Symbols["zs"]
+	require.NotNil(t, zs)
+	iterable := zs.NamedChild(1).ChildByFieldName("right")
+	assert.Equal(t, "[1, 2]", lookup(iterable))
+	body := zs.ChildByFieldName("body")
+	assert.Equal(t, "for_in_clause", GetScope(module, body).Lookup("xs").Parent().Type())
+}
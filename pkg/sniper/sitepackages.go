@@ -0,0 +1,189 @@
+package sniper
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srijanpaul-deepsource/reachable/pkg/util"
+)
+
+// Distribution is a python package installed in a site-packages directory.
+type Distribution struct {
+	// Name is the name of the distribution on PyPI (e.g: `PyYAML`)
+	Name string
+	// Version is the installed version
+	Version string
+	// MetadataDir is the `.dist-info` (or `.egg-info`) directory of the distribution
+	MetadataDir string
+}
+
+// SitePackages is an index of all distributions
+// installed in a site-packages directory.
+type SitePackages struct {
+	Path string
+	// distributions maps the normalized name of a distribution
+	// (see `util.NormalizePythonPackageName`) to its metadata
+	distributions map[string]*Distribution
+	// distributionOfFile maps the absolute path of every file listed
+	// in a distribution's RECORD to that distribution
+	distributionOfFile map[string]*Distribution
+	// distributionOfTopLevel maps a top-level import name (e.g: `yaml`)
+	// to the distribution that provides it (e.g: `PyYAML`).
+	// This is read from `top_level.txt`.
+	distributionOfTopLevel map[string]*Distribution
+}
+
+// LoadSitePackages reads the metadata of every distribution installed
+// in the site-packages directory at `path`.
+func LoadSitePackages(path string) *SitePackages {
+	sitePackages := &SitePackages{
+		Path:                   path,
+		distributions:          make(map[string]*Distribution),
+		distributionOfFile:     make(map[string]*Distribution),
+		distributionOfTopLevel: make(map[string]*Distribution),
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return sitePackages
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		var metadataFile string
+		switch filepath.Ext(entry.Name()) {
+		case ".dist-info":
+			metadataFile = "METADATA"
+		case ".egg-info":
+			metadataFile = "PKG-INFO"
+		default:
+			continue
+		}
+
+		metadataDir := filepath.Join(path, entry.Name())
+		dist := readDistMetadata(filepath.Join(metadataDir, metadataFile))
+		if dist == nil {
+			continue
+		}
+
+		dist.MetadataDir = metadataDir
+		sitePackages.distributions[util.NormalizePythonPackageName(dist.Name)] = dist
+		sitePackages.indexFiles(dist)
+	}
+
+	return sitePackages
+}
+
+// indexFiles adds the files installed by `dist` to the file and top-level name indexes.
+func (sp *SitePackages) indexFiles(dist *Distribution) {
+	// Every line in RECORD is "<path>,<hash>,<size>",
+	// where the path is relative to the site-packages directory.
+	for _, line := range readLines(filepath.Join(dist.MetadataDir, "RECORD")) {
+		relPath, _, _ := strings.Cut(line, ",")
+		if relPath == "" {
+			continue
+		}
+
+		filePath := filepath.Clean(filepath.Join(sp.Path, filepath.FromSlash(relPath)))
+		sp.distributionOfFile[filePath] = dist
+	}
+
+	for _, line := range readLines(filepath.Join(dist.MetadataDir, "top_level.txt")) {
+		if name := strings.TrimSpace(line); name != "" {
+			sp.distributionOfTopLevel[name] = dist
+		}
+	}
+}
+
+// DistributionOfFile finds the installed distribution that a file belongs to.
+// The RECORD of every distribution is checked first, and if no distribution lists the
+// file, it is looked up by its top-level import name in `top_level.txt` files.
+func (sp *SitePackages) DistributionOfFile(filePath string) *Distribution {
+	filePath = filepath.Clean(filePath)
+	if dist := sp.distributionOfFile[filePath]; dist != nil {
+		return dist
+	}
+
+	relPath, err := filepath.Rel(sp.Path, filePath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return nil
+	}
+
+	topLevel, _, _ := strings.Cut(relPath, string(filepath.Separator))
+	topLevel = strings.TrimSuffix(topLevel, ".py")
+	if dist := sp.distributionOfTopLevel[topLevel]; dist != nil {
+		return dist
+	}
+
+	// Most distributions have the same name as their top-level package.
+	return sp.Distribution(topLevel)
+}
+
+// readLines returns all lines in a file, or nil if it could not be read.
+func readLines(path string) []string {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	return strings.Split(strings.ReplaceAll(string(contents), "\r\n", "\n"), "\n")
+}
+
+// Distribution finds an installed distribution by its name.
+func (sp *SitePackages) Distribution(name string) *Distribution {
+	return sp.distributions[util.NormalizePythonPackageName(name)]
+}
+
+// readDistMetadata reads the name and version of a distribution
+// from the headers of its core metadata file (METADATA or PKG-INFO).
+func readDistMetadata(path string) *Distribution {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	dist := &Distribution{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// The headers end at the first blank line,
+			// and the long description starts after it.
+			break
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "Name":
+			dist.Name = strings.TrimSpace(value)
+		case "Version":
+			dist.Version = strings.TrimSpace(value)
+		}
+	}
+
+	if dist.Name == "" || dist.Version == "" {
+		return nil
+	}
+
+	return dist
+}
+
+// sitePackagesDirOf returns the site-packages directory that contains `filePath`, if any.
+func sitePackagesDirOf(filePath string) *string {
+	for dir := filepath.Dir(filePath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == "site-packages" || filepath.Base(dir) == "dist-packages" {
+			return &dir
+		}
+	}
+
+	return nil
+}
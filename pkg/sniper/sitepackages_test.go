@@ -0,0 +1,74 @@
+package sniper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/srijanpaul-deepsource/reachable/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PackageVersion(t *testing.T) {
+	sitePackages := filepath.Join(t.TempDir(), "venv", "lib", "python3.12", "site-packages")
+	testutil.WriteFiles(t, sitePackages, map[string]string{
+		"requests/__init__.py": "def get(url):\n\tpass\n",
+		"requests-2.31.0.dist-info/METADATA": "Metadata-Version: 2.1\n" +
+			"Name: requests\nVersion: 2.31.0\n\nName: not-a-header\n",
+		"Python_Dateutil-2.9.0.dist-info/METADATA": "Name: python-dateutil\nVersion: 2.9.0\n",
+	})
+
+	filePath := filepath.Join(sitePackages, "requests", "__init__.py")
+	contents, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+
+	py, err := ParsePython(filePath, contents)
+	require.NoError(t, err)
+
+	version := py.PackageVersion()
+	require.NotNil(t, version)
+	assert.Equal(t, "2.31.0", *version)
+
+	// Files that are parsed with the same search path share the index of their site-packages
+	assert.Same(t, py.SysPath.SitePackagesAt(sitePackages), py.SysPath.SitePackagesAt(sitePackages))
+
+	dist := LoadSitePackages(sitePackages).Distribution("Python.Dateutil")
+	require.NotNil(t, dist)
+	assert.Equal(t, "python-dateutil", dist.Name)
+	assert.Equal(t, "2.9.0", dist.Version)
+}
+
+func Test_PackageNameFromRecord(t *testing.T) {
+	sitePackages := filepath.Join(t.TempDir(), "venv", "lib", "python3.12", "site-packages")
+	testutil.WriteFiles(t, sitePackages, map[string]string{
+		"yaml/__init__.py":                "def load(stream):\n\tpass\n",
+		"_yaml/__init__.py":               "",
+		"PyYAML-6.0.1.dist-info/METADATA": "Name: PyYAML\nVersion: 6.0.1\n",
+		"PyYAML-6.0.1.dist-info/RECORD": "yaml/__init__.py,sha256=abc,100\n" +
+			"PyYAML-6.0.1.dist-info/METADATA,,\n",
+		"PyYAML-6.0.1.dist-info/top_level.txt": "_yaml\nyaml\n",
+		"jwt/api_jwt.py":                       "def decode(token):\n\tpass\n",
+		"PyJWT-2.8.0.dist-info/METADATA":       "Name: PyJWT\nVersion: 2.8.0\n",
+		"PyJWT-2.8.0.dist-info/top_level.txt":  "jwt\n",
+	})
+
+	cases := map[string]string{
+		"yaml/__init__.py":  "PyYAML",
+		"_yaml/__init__.py": "PyYAML",
+		"jwt/api_jwt.py":    "PyJWT",
+	}
+
+	for relPath, want := range cases {
+		filePath := filepath.Join(sitePackages, relPath)
+		contents, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+
+		py, err := ParsePython(filePath, contents)
+		require.NoError(t, err)
+
+		packageName := py.PackageName()
+		require.NotNil(t, packageName)
+		assert.Equal(t, want, *packageName, relPath)
+	}
+}
@@ -0,0 +1,95 @@
+package sniper
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// stubOf returns the parsed stub file of a module (see `ParsedFile.StubFilePath`), or nil if it has none.
+func (cg *CallGraph) stubOf(file ParsedFile) ParsedFile {
+	fileName := file.Module().FileName
+	if stub, cached := cg.stubs[fileName]; cached {
+		return stub
+	}
+
+	var stub ParsedFile
+	if stubPath := file.StubFilePath(); stubPath != nil {
+		stub = cg.parseImportedFile(file, *stubPath)
+	}
+
+	cg.stubs[fileName] = stub
+	return stub
+}
+
+// stubDefinitionsOf returns the declarations of a class or function in the stub of its module.
+// Returns nil if the module has no stub, or if the stub doesn't declare the definition.
+func (cg *CallGraph) stubDefinitionsOf(file ParsedFile, def *sitter.Node) []flowValue {
+	stub := cg.stubOf(file)
+	if stub == nil {
+		return nil
+	}
+
+	return cg.counterpartsOf(file, def, stub)
+}
+
+// implementationsOf returns the definitions that a declaration in a stub file describes, in the source
+// of its module. Call graph nodes are created for the source, since that's the code that runs.
+// Definitions outside of stubs, and declarations that have no source, are returned as they are.
+func (cg *CallGraph) implementationsOf(file ParsedFile, def *sitter.Node) []flowValue {
+	sourcePath := file.ImplementationFilePath()
+	if sourcePath == nil {
+		return asValues(file, def)
+	}
+
+	source := cg.parseImportedFile(file, *sourcePath)
+	if source == nil {
+		return asValues(file, def)
+	}
+
+	if impls := cg.counterpartsOf(file, def, source); len(impls) > 0 {
+		return impls
+	}
+
+	return asValues(file, def)
+}
+
+// counterpartsOf finds the class or function definitions in `other` (the source or the stub of the
+// same module as `file`) that have the same qualified name as `def`. Names that `other` imports are
+// followed to their definitions, since a module's source may define them somewhere else. A name can
+// be defined more than once (e.g: in both branches of an `if`), so every definition is followed.
+func (cg *CallGraph) counterpartsOf(file ParsedFile, def *sitter.Node, other ParsedFile) []flowValue {
+	qualifiedName := file.QualifiedNameOf(def)
+	if qualifiedName == nil {
+		return nil
+	}
+
+	defs := asValues(other, other.Module().Ast)
+	for _, name := range strings.Split(*qualifiedName, ".") {
+		var members []flowValue
+		for _, parent := range defs {
+			scope := parent.file.Module().ScopeOfNode[parent.node]
+			if scope == nil {
+				continue
+			}
+
+			for _, decl := range scope.Definitions[name] {
+				if parent.file.IsImport(decl) {
+					members = append(members, cg.resolveImport(parent.file, decl, name)...)
+				} else {
+					members = append(members, flowValue{file: parent.file, node: decl})
+				}
+			}
+		}
+		defs = members
+	}
+
+	var counterparts []flowValue
+	for _, def := range defs {
+		if def.file.IsClassDef(def.node) || def.file.IsFunctionDef(def.node) {
+			counterparts = append(counterparts, def)
+		}
+	}
+
+	return counterparts
+}
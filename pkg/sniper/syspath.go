@@ -0,0 +1,272 @@
+package sniper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// PythonEnv describes the python environment that a program runs in.
+// All fields are optional.
+type PythonEnv struct {
+	// VenvPath is the root directory of a virtual environment (the one with `pyvenv.cfg`).
+	// When empty, a virtual environment inside the project root is used, if there is one.
+	VenvPath string
+	// ExtraPaths are searched for modules before site-packages, like the
+	// directories in the PYTHONPATH environment variable.
+	ExtraPaths []string
+	// TypeshedPath is the root directory of a local checkout of typeshed
+	// (https://github.com/python/typeshed), whose stubs describe modules
+	// that have no python source, like the builtin modules of the stdlib.
+	TypeshedPath string
+}
+
+// SysPath is an ordered list of directories that are searched for
+// imported modules, like `sys.path` in a running python program.
+type SysPath struct {
+	Roots []string
+	// StubRoots are directories that only have stub files (`.pyi`), like the
+	// `stdlib` and `stubs/*` directories of typeshed. They are searched for
+	// stubs after `Roots`, and for modules that can't be found in `Roots`.
+	StubRoots []string
+	// sitePackages maps a site-packages directory to its index (see `SitePackagesAt`),
+	// so that every file that is imported from the same directory shares the index.
+	sitePackages   map[string]*SitePackages
+	sitePackagesMu sync.Mutex
+}
+
+// NewSysPath builds the module search path for a project.
+// The directories are searched in this order:
+//  1. The project root, and its `src` directory (for projects that use the src-layout).
+//  2. Extra paths from `env` (PYTHONPATH).
+//  3. The standard library of the python installation that the venv was created from.
+//  4. The venv's site-packages directory, followed by directories added by `.pth` files in it.
+//
+// The stubs in `env.TypeshedPath` are searched after all of these.
+func NewSysPath(projectRoot string, env PythonEnv) *SysPath {
+	sysPath := &SysPath{}
+	if env.TypeshedPath != "" {
+		sysPath.StubRoots = typeshedRootsOf(env.TypeshedPath)
+	}
+
+	sysPath.add(projectRoot)
+	sysPath.add(filepath.Join(projectRoot, "src"))
+
+	for _, path := range env.ExtraPaths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectRoot, path)
+		}
+		sysPath.add(path)
+	}
+
+	venvPath := env.VenvPath
+	if venvPath == "" {
+		venvPath = findVenv(projectRoot)
+	}
+
+	if venvPath == "" {
+		return sysPath
+	}
+
+	venvConfig := readPyvenvCfg(filepath.Join(venvPath, "pyvenv.cfg"))
+	if stdlib := stdlibPathOf(venvConfig); stdlib != "" {
+		sysPath.add(stdlib)
+	}
+
+	for _, sitePackages := range sitePackagesDirsOf(venvPath) {
+		sysPath.add(sitePackages)
+		for _, path := range readPthFiles(sitePackages) {
+			sysPath.add(path)
+		}
+	}
+
+	return sysPath
+}
+
+// add appends a directory to the search path if it
+// exists and isn't already in the search path.
+func (sp *SysPath) add(dir string) {
+	dir = filepath.Clean(dir)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return
+	}
+
+	for _, root := range sp.Roots {
+		if root == dir {
+			return
+		}
+	}
+
+	sp.Roots = append(sp.Roots, dir)
+}
+
+// SitePackagesAt returns the index of the site-packages directory at `path`,
+// which is loaded the first time it is needed.
+func (sp *SysPath) SitePackagesAt(path string) *SitePackages {
+	sp.sitePackagesMu.Lock()
+	defer sp.sitePackagesMu.Unlock()
+
+	if sitePackages, cached := sp.sitePackages[path]; cached {
+		return sitePackages
+	}
+
+	if sp.sitePackages == nil {
+		sp.sitePackages = make(map[string]*SitePackages)
+	}
+
+	sitePackages := LoadSitePackages(path)
+	sp.sitePackages[path] = sitePackages
+	return sitePackages
+}
+
+// RootOf returns the directory in the search path (or the stub roots) that `filePath` is imported
+// relative to. When multiple roots contain the file, the innermost one wins
+// (e.g: `project/venv/.../site-packages` over `project`).
+func (sp *SysPath) RootOf(filePath string) *string {
+	var bestRoot *string
+	for _, roots := range [][]string{sp.Roots, sp.StubRoots} {
+		for i, root := range roots {
+			if !strings.HasPrefix(filePath, root+string(filepath.Separator)) {
+				continue
+			}
+
+			if bestRoot == nil || len(root) > len(*bestRoot) {
+				bestRoot = &roots[i]
+			}
+		}
+	}
+
+	return bestRoot
+}
+
+// FindStubFile searches for the stub file (`.pyi`) of a module, given its dotted name.
+// Stub-only packages (PEP 561) in the search path are searched first (e.g: `requests-stubs/`
+// for `requests`), followed by the stub roots. Stubs that sit next to the module's source
+// aren't searched for here, since they're found relative to the source file.
+// Returns "" when there is no stub.
+func (sp *SysPath) FindStubFile(moduleName string) string {
+	topLevel, rest, _ := strings.Cut(moduleName, ".")
+	stubPackage := topLevel + "-stubs"
+	if rest != "" {
+		stubPackage += "." + rest
+	}
+
+	if filePath, _ := findModuleFileWithExts(sp.Roots, stubPackage, ".pyi"); filePath != "" {
+		return filePath
+	}
+
+	filePath, _ := findModuleFileWithExts(sp.StubRoots, moduleName, ".pyi")
+	return filePath
+}
+
+// typeshedRootsOf returns the stub roots in a checkout of typeshed: the `stdlib`
+// directory, and the directory of every third-party distribution in `stubs`.
+func typeshedRootsOf(typeshedPath string) []string {
+	roots := &SysPath{}
+	roots.add(filepath.Join(typeshedPath, "stdlib"))
+
+	distributions, _ := os.ReadDir(filepath.Join(typeshedPath, "stubs"))
+	for _, dist := range distributions {
+		if dist.IsDir() {
+			roots.add(filepath.Join(typeshedPath, "stubs", dist.Name()))
+		}
+	}
+
+	return roots.Roots
+}
+
+// findVenv looks for a virtual environment in the immediate
+// sub-directories of the project root.
+func findVenv(projectRoot string) string {
+	entries, err := os.ReadDir(projectRoot)
+	if err != nil {
+		return ""
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		venvPath := filepath.Join(projectRoot, entry.Name())
+		if _, err := os.Stat(filepath.Join(venvPath, "pyvenv.cfg")); err == nil {
+			return venvPath
+		}
+	}
+
+	return ""
+}
+
+// readPyvenvCfg parses the "key = value" pairs in a `pyvenv.cfg` file.
+func readPyvenvCfg(path string) map[string]string {
+	config := make(map[string]string)
+	for _, line := range readLines(path) {
+		key, value, found := strings.Cut(line, "=")
+		if found {
+			config[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return config
+}
+
+// stdlibPathOf finds the standard library directory of the python installation
+// that a venv was created from. `home` in pyvenv.cfg is the directory of the base
+// interpreter (e.g: `/usr/bin`), and the stdlib lives in `<home>/../lib/pythonX.Y`.
+func stdlibPathOf(venvConfig map[string]string) string {
+	home := venvConfig["home"]
+	version := venvConfig["version_info"]
+	if version == "" {
+		version = venvConfig["version"]
+	}
+
+	if home == "" || version == "" {
+		return ""
+	}
+
+	versionParts := strings.Split(version, ".")
+	if len(versionParts) < 2 {
+		return ""
+	}
+
+	pythonDir := "python" + versionParts[0] + "." + versionParts[1]
+	return filepath.Join(filepath.Dir(home), "lib", pythonDir)
+}
+
+// sitePackagesDirsOf returns the site-packages directories in a venv.
+func sitePackagesDirsOf(venvPath string) []string {
+	// POSIX venvs use `lib/pythonX.Y/site-packages`, Windows venvs use `Lib/site-packages`.
+	dirs, _ := filepath.Glob(filepath.Join(venvPath, "lib", "python*", "site-packages"))
+	windowsDir := filepath.Join(venvPath, "Lib", "site-packages")
+	if _, err := os.Stat(windowsDir); err == nil {
+		dirs = append(dirs, windowsDir)
+	}
+
+	return dirs
+}
+
+// readPthFiles returns the directories that `.pth` files in a
+// site-packages directory add to the search path.
+// Lines that start with `import` are executable code, and are skipped.
+func readPthFiles(sitePackages string) []string {
+	pthFiles, _ := filepath.Glob(filepath.Join(sitePackages, "*.pth"))
+
+	var dirs []string
+	for _, pthFile := range pthFiles {
+		for _, line := range readLines(pthFile) {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") ||
+				strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "import\t") {
+				continue
+			}
+
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(sitePackages, line)
+			}
+			dirs = append(dirs, line)
+		}
+	}
+
+	return dirs
+}
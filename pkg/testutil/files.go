@@ -0,0 +1,21 @@
+// Package testutil has helpers that are shared by the tests of other packages.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// WriteFiles writes files (that map a path relative to `root` to their contents)
+// under `root`, creating the directories that they're in.
+func WriteFiles(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	for relPath, contents := range files {
+		path := filepath.Join(root, relPath)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+}
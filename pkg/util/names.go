@@ -0,0 +1,12 @@
+package util
+
+import "strings"
+
+// NormalizePythonPackageName normalizes the name of a python distribution as described in PEP 503.
+// e.g: `Python_Dateutil` -> `python-dateutil`
+func NormalizePythonPackageName(name string) string {
+	name = strings.ToLower(name)
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	}), "-")
+}
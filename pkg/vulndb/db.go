@@ -0,0 +1,228 @@
+package vulndb
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/osv-scanner/pkg/grouper"
+	"github.com/google/osv-scanner/pkg/lockfile"
+	"github.com/google/osv-scanner/pkg/models"
+	"github.com/srijanpaul-deepsource/reachable/pkg/util"
+)
+
+// DB is an in-memory copy of an OSV advisory dump.
+// It lets us match lockfile packages against advisories without
+// talking to api.osv.dev.
+type DB struct {
+	// vulnsOfPackage maps a package key (see `packageKey`)
+	// to all advisories that mention the package.
+	vulnsOfPackage map[string][]models.Vulnerability
+	// Skipped are the errors of the JSON files in the dump that couldn't be
+	// parsed as OSV advisories. These files are skipped instead of failing the load.
+	Skipped []error
+}
+
+// Load reads an OSV dump from disk.
+// `path` can be a single OSV JSON file, a zip archive of OSV JSON files
+// (like the per-ecosystem `all.zip` files that OSV publishes), or a directory
+// that contains any number of either.
+func Load(path string) (*DB, error) {
+	db := &DB{vulnsOfPackage: make(map[string][]models.Vulnerability)}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load OSV database: %v", err)
+	}
+
+	if !info.IsDir() {
+		if err := db.loadFile(path); err != nil {
+			return nil, err
+		}
+
+		// A JSON file that is given on its own must be an advisory
+		if strings.EqualFold(filepath.Ext(path), ".json") && len(db.Skipped) > 0 {
+			return nil, db.Skipped[0]
+		}
+		return db, nil
+	}
+
+	err = filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		return db.loadFile(filePath)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// loadFile loads advisories from a JSON or zip file.
+// Files with any other extension are ignored, and so are
+// JSON files that aren't advisories (see `DB.Skipped`).
+func (db *DB) loadFile(filePath string) error {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		contents, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		db.loadJSON(filePath, contents)
+
+	case ".zip":
+		return db.loadZip(filePath)
+	}
+
+	return nil
+}
+
+func (db *DB) loadZip(zipPath string) error {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", zipPath, err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if !strings.HasSuffix(file.Name, ".json") {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("could not read %s in %s: %v", file.Name, zipPath, err)
+		}
+
+		contents, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("could not read %s in %s: %v", file.Name, zipPath, err)
+		}
+
+		db.loadJSON(zipPath+":"+file.Name, contents)
+	}
+
+	return nil
+}
+
+// loadJSON loads an advisory from the contents of a JSON file. A file that can't be
+// parsed, or that isn't an OSV advisory (which always has an `id`), is skipped.
+func (db *DB) loadJSON(source string, contents []byte) {
+	var vuln models.Vulnerability
+	if err := json.Unmarshal(contents, &vuln); err != nil {
+		db.Skipped = append(db.Skipped, fmt.Errorf("could not parse advisory %s: %v", source, err))
+		return
+	}
+
+	if vuln.ID == "" {
+		db.Skipped = append(db.Skipped, fmt.Errorf("%s is not an OSV advisory: it has no id", source))
+		return
+	}
+
+	db.Add(vuln)
+}
+
+// Add inserts an advisory into the database.
+// Withdrawn advisories are dropped.
+func (db *DB) Add(vuln models.Vulnerability) {
+	if !vuln.Withdrawn.IsZero() {
+		return
+	}
+
+	seen := make(map[string]struct{})
+	for _, affected := range vuln.Affected {
+		key := packageKey(string(affected.Package.Ecosystem), affected.Package.Name)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		db.vulnsOfPackage[key] = append(db.vulnsOfPackage[key], vuln)
+	}
+}
+
+// VulnerabilitiesOf returns all advisories that affect a
+// specific version of a package.
+func (db *DB) VulnerabilitiesOf(ecosystem, name, version string) []models.Vulnerability {
+	var vulns []models.Vulnerability
+	for _, vuln := range db.vulnsOfPackage[packageKey(ecosystem, name)] {
+		if IsAffected(vuln, ecosystem, name, version) {
+			vulns = append(vulns, vuln)
+		}
+	}
+
+	return vulns
+}
+
+// Scan parses a lockfile and matches every package in it against the database.
+// The result has the same shape as the one returned by `osvscanner.DoScan`.
+func (db *DB) Scan(lockfilePath string) (models.VulnerabilityResults, error) {
+	parsedLockfile, err := lockfile.Parse(lockfilePath, "")
+	if err != nil {
+		return models.VulnerabilityResults{}, err
+	}
+
+	source := models.PackageSource{
+		Source: models.SourceInfo{Path: lockfilePath, Type: "lockfile"},
+	}
+
+	for _, pkg := range parsedLockfile.Packages {
+		vulns := db.VulnerabilitiesOf(string(pkg.Ecosystem), pkg.Name, pkg.Version)
+		if len(vulns) == 0 {
+			continue
+		}
+
+		source.Packages = append(source.Packages, models.PackageVulns{
+			Package: models.PackageInfo{
+				Name:      pkg.Name,
+				Version:   pkg.Version,
+				Ecosystem: string(pkg.Ecosystem),
+			},
+			DepGroups:       pkg.DepGroups,
+			Vulnerabilities: vulns,
+			Groups:          grouper.Group(grouper.ConvertVulnerabilityToIDAliases(vulns)),
+		})
+	}
+
+	report := models.VulnerabilityResults{}
+	if len(source.Packages) > 0 {
+		report.Results = append(report.Results, source)
+	}
+
+	return report, nil
+}
+
+// packageKey returns a key that uniquely identifies a package within the database.
+func packageKey(ecosystem, name string) string {
+	return baseEcosystem(ecosystem) + "/" + NormalizeName(ecosystem, name)
+}
+
+// baseEcosystem strips the release suffix from an ecosystem name (e.g: "Debian:11" -> "Debian")
+func baseEcosystem(ecosystem string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return base
+}
+
+// NormalizeName returns the canonical form of a package name in an ecosystem.
+// For PyPI, this is the normalization described in PEP 503.
+func NormalizeName(ecosystem, name string) string {
+	if baseEcosystem(ecosystem) != string(models.EcosystemPyPI) {
+		return name
+	}
+
+	return util.NormalizePythonPackageName(name)
+}
@@ -0,0 +1,207 @@
+package vulndb
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/osv-scanner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const requestsAdvisory = `{
+	"id": "GHSA-j8r2-6x86-q33q",
+	"aliases": ["CVE-2023-32681"],
+	"summary": "Unintended leak of Proxy-Authorization header in requests",
+	"affected": [{
+		"package": {"ecosystem": "PyPI", "name": "requests"},
+		"ranges": [{
+			"type": "ECOSYSTEM",
+			"events": [{"introduced": "2.3.0"}, {"fixed": "2.31.0"}]
+		}]
+	}]
+}`
+
+const starletteAdvisory = `{
+	"id": "PYSEC-2023-48",
+	"summary": "Denial of service in starlette",
+	"affected": [{
+		"package": {"ecosystem": "PyPI", "name": "Starlette"},
+		"ranges": [{
+			"type": "ECOSYSTEM",
+			"events": [{"introduced": "0"}, {"last_affected": "0.25.0"}]
+		}]
+	}]
+}`
+
+const withdrawnAdvisory = `{
+	"id": "GHSA-xxxx-xxxx-xxxx",
+	"withdrawn": "2023-01-01T00:00:00Z",
+	"affected": [{
+		"package": {"ecosystem": "PyPI", "name": "idna"},
+		"versions": ["3.7"]
+	}]
+}`
+
+const requirementsTxt = `
+requests==2.30.0
+starlette==0.11.1
+idna==3.7
+urllib3==2.2.2
+`
+
+func writeFile(t *testing.T, path string, contents string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	out, err := os.Create(path)
+	require.NoError(t, err)
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	for name, contents := range files {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+}
+
+func vulnIdsOf(report models.VulnerabilityResults) map[string][]string {
+	ids := make(map[string][]string)
+	for _, result := range report.Results {
+		for _, pkg := range result.Packages {
+			for _, vuln := range pkg.Vulnerabilities {
+				ids[pkg.Package.Name] = append(ids[pkg.Package.Name], vuln.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func Test_ScanDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "db", "PyPI", "GHSA-j8r2-6x86-q33q.json"), requestsAdvisory)
+	writeFile(t, filepath.Join(dir, "db", "PyPI", "PYSEC-2023-48.json"), starletteAdvisory)
+	writeFile(t, filepath.Join(dir, "db", "PyPI", "GHSA-xxxx-xxxx-xxxx.json"), withdrawnAdvisory)
+	// JSON files that aren't advisories are skipped
+	writeFile(t, filepath.Join(dir, "db", "PyPI", "truncated.json"), requestsAdvisory[:40])
+	writeFile(t, filepath.Join(dir, "db", "schema.json"), `{"type": "object"}`)
+	writeFile(t, filepath.Join(dir, "requirements.txt"), requirementsTxt)
+
+	db, err := Load(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	assert.Len(t, db.Skipped, 2)
+
+	report, err := db.Scan(filepath.Join(dir, "requirements.txt"))
+	require.NoError(t, err)
+
+	want := map[string][]string{
+		"requests":  {"GHSA-j8r2-6x86-q33q"},
+		"starlette": {"PYSEC-2023-48"},
+	}
+	assert.Equal(t, want, vulnIdsOf(report))
+}
+
+func Test_ScanZip(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "all.zip"), map[string]string{
+		"GHSA-j8r2-6x86-q33q.json": requestsAdvisory,
+		"PYSEC-2023-48.json":       starletteAdvisory,
+		"truncated.json":           requestsAdvisory[:40],
+	})
+	writeFile(t, filepath.Join(dir, "requirements.txt"), "requests==2.31.0\nstarlette==0.26.0\n")
+
+	db, err := Load(filepath.Join(dir, "all.zip"))
+	require.NoError(t, err)
+	assert.Len(t, db.Skipped, 1)
+
+	report, err := db.Scan(filepath.Join(dir, "requirements.txt"))
+	require.NoError(t, err)
+	assert.Empty(t, report.Results)
+}
+
+func Test_LoadInvalidAdvisoryFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "schema.json"), `{"type": "object"}`)
+
+	_, err := Load(filepath.Join(dir, "schema.json"))
+	assert.Error(t, err)
+}
+
+func Test_IsAffected(t *testing.T) {
+	vuln := models.Vulnerability{
+		ID: "TEST-1",
+		Affected: []models.Affected{{
+			Package: models.Package{Ecosystem: "PyPI", Name: "python-dateutil"},
+			Ranges: []models.Range{{
+				Type: models.RangeEcosystem,
+				Events: []models.Event{
+					{Introduced: "1.0"}, {Fixed: "1.5"},
+					{Introduced: "2.0"}, {LastAffected: "2.1.3"},
+				},
+			}},
+			Versions: []string{"0.9"},
+		}},
+	}
+
+	cases := map[string]bool{
+		"0.8":   false,
+		"0.9":   true,
+		"1.0":   true,
+		"1.4.9": true,
+		"1.5":   false,
+		"1.9":   false,
+		"2.0":   true,
+		"2.1.3": true,
+		"2.1.4": false,
+	}
+
+	for version, want := range cases {
+		assert.Equal(t, want, IsAffected(vuln, "PyPI", "Python_Dateutil", version), version)
+	}
+}
+
+func Test_SymbolsOf(t *testing.T) {
+	vuln := models.Vulnerability{
+		ID: "TEST-2",
+		Affected: []models.Affected{{
+			Package: models.Package{Ecosystem: "PyPI", Name: "requests"},
+			EcosystemSpecific: map[string]interface{}{
+				"imports": []interface{}{
+					map[string]interface{}{
+						"path":    "requests",
+						"symbols": []interface{}{"Session.rebuild_proxies"},
+					},
+				},
+			},
+			DatabaseSpecific: map[string]interface{}{
+				"affected_functions": []interface{}{"requests.utils.get_netrc_auth"},
+			},
+		}},
+	}
+
+	symbols := SymbolsOf(vuln, "PyPI", "requests")
+	require.Len(t, symbols, 2)
+	assert.Empty(t, SymbolsOf(vuln, "PyPI", "urllib3"))
+
+	matchesAny := func(moduleName, qualifiedName string) bool {
+		for _, symbol := range symbols {
+			if symbol.Matches(moduleName, qualifiedName) {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.True(t, matchesAny("requests.sessions", "Session.rebuild_proxies"))
+	assert.True(t, matchesAny("requests.utils", "get_netrc_auth"))
+	assert.False(t, matchesAny("requests.sessions", "Session.request"))
+	assert.False(t, matchesAny("requests.utils", "get_netrc_auth_v2"))
+	assert.False(t, matchesAny("requestsx", "Session.rebuild_proxies"))
+}
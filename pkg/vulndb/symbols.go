@@ -0,0 +1,108 @@
+package vulndb
+
+import (
+	"strings"
+
+	"github.com/google/osv-scanner/pkg/models"
+)
+
+// Symbol is a function, method or class that an advisory
+// names as the location of a vulnerability.
+type Symbol struct {
+	// Module is the dotted import path that the symbol is exported from (e.g: `requests.sessions`).
+	// This is empty when `Name` is already fully qualified.
+	Module string
+	// Name is the name of the symbol within `Module` (e.g: `Session.request`).
+	Name string
+}
+
+// Matches returns `true` if a function with the qualified name `qualifiedName`
+// defined in the module `moduleName` is (or belongs to) this symbol.
+// Symbols exported from a package also match definitions in its sub-modules,
+// since packages usually re-export their API from `__init__.py`.
+func (s Symbol) Matches(moduleName, qualifiedName string) bool {
+	isPrefixOf := func(prefix, name string) bool {
+		return name == prefix || strings.HasPrefix(name, prefix+".")
+	}
+
+	if s.Module == "" {
+		return isPrefixOf(s.Name, moduleName+"."+qualifiedName)
+	}
+
+	return isPrefixOf(s.Module, moduleName) && isPrefixOf(s.Name, qualifiedName)
+}
+
+// SymbolsOf returns the vulnerable symbols that an advisory lists for a package.
+// Symbols are read from `affected[].ecosystem_specific.imports[]` (the format used by
+// the Go vulnerability database), and from the `affected_functions` lists that
+// PYSEC and GHSA advisories carry in `ecosystem_specific` or `database_specific`.
+// An empty result means the advisory has no symbol-level information.
+func SymbolsOf(vuln models.Vulnerability, ecosystem, name string) []Symbol {
+	key := packageKey(ecosystem, name)
+
+	var symbols []Symbol
+	for _, affected := range vuln.Affected {
+		if packageKey(string(affected.Package.Ecosystem), affected.Package.Name) != key {
+			continue
+		}
+
+		symbols = append(symbols, importedSymbols(affected.EcosystemSpecific)...)
+		symbols = append(symbols, affectedFunctions(affected.EcosystemSpecific)...)
+		symbols = append(symbols, affectedFunctions(affected.DatabaseSpecific)...)
+	}
+
+	return symbols
+}
+
+// importedSymbols reads symbols from an `imports` list:
+// "imports": [{"path": "requests.sessions", "symbols": ["Session.request"]}]
+func importedSymbols(fields map[string]interface{}) []Symbol {
+	imports, ok := fields["imports"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var symbols []Symbol
+	for _, entry := range imports {
+		entry, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		path, _ := entry["path"].(string)
+		for _, name := range stringsOf(entry["symbols"]) {
+			symbols = append(symbols, Symbol{Module: path, Name: name})
+		}
+	}
+
+	return symbols
+}
+
+// affectedFunctions reads fully qualified symbols from an `affected_functions` list:
+// "affected_functions": ["requests.sessions.Session.request"]
+func affectedFunctions(fields map[string]interface{}) []Symbol {
+	var symbols []Symbol
+	for _, name := range stringsOf(fields["affected_functions"]) {
+		symbols = append(symbols, Symbol{Name: name})
+	}
+
+	return symbols
+}
+
+// stringsOf converts a decoded JSON array to a list of strings,
+// skipping any elements that aren't strings.
+func stringsOf(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var strs []string
+	for _, elem := range list {
+		if str, ok := elem.(string); ok && str != "" {
+			strs = append(strs, str)
+		}
+	}
+
+	return strs
+}
@@ -0,0 +1,153 @@
+package vulndb
+
+import (
+	"slices"
+
+	"deps.dev/util/semver"
+	"github.com/google/osv-scanner/pkg/models"
+)
+
+// versionSystemOf returns the version ordering rules used by an ecosystem.
+func versionSystemOf(ecosystem string) semver.System {
+	switch models.Ecosystem(baseEcosystem(ecosystem)) {
+	case models.EcosystemPyPI:
+		return semver.PyPI
+	case models.EcosystemNPM:
+		return semver.NPM
+	case models.EcosystemGo:
+		return semver.Go
+	case models.EcosystemMaven:
+		return semver.Maven
+	case models.EcosystemNuGet:
+		return semver.NuGet
+	case models.EcosystemRubyGems:
+		return semver.RubyGems
+	case models.EcosystemCratesIO:
+		return semver.Cargo
+	case models.EcosystemPackagist:
+		return semver.Composer
+	}
+
+	return semver.DefaultSystem
+}
+
+// IsAffected returns `true` if `version` of the package `name` is
+// affected by the advisory `vuln`.
+func IsAffected(vuln models.Vulnerability, ecosystem, name, version string) bool {
+	key := packageKey(ecosystem, name)
+	for _, affected := range vuln.Affected {
+		pkg := affected.Package
+		if packageKey(string(pkg.Ecosystem), pkg.Name) != key {
+			continue
+		}
+
+		if affectsVersion(affected, versionSystemOf(ecosystem), version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// affectsVersion checks a version against the explicit versions
+// and the ECOSYSTEM/SEMVER ranges of an `affected` entry.
+// GIT ranges are ignored, since lockfiles give us versions, not commits.
+func affectsVersion(affected models.Affected, sys semver.System, version string) bool {
+	if slices.Contains(affected.Versions, version) {
+		return true
+	}
+
+	v, err := sys.Parse(version)
+	if err != nil {
+		return false
+	}
+
+	for _, r := range affected.Ranges {
+		if r.Type != models.RangeEcosystem && r.Type != models.RangeSemVer {
+			continue
+		}
+
+		if rangeContains(r, sys, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rangeContains evaluates the events of an OSV range against a version,
+// following the algorithm in the OSV schema:
+// https://ossf.github.io/osv-schema/#evaluation
+//
+// `v` is affected if the latest `introduced` event at or below `v`
+// is not followed by a `fixed` or `last_affected` event that closes
+// the range before reaching `v`.
+func rangeContains(r models.Range, sys semver.System, v *semver.Version) bool {
+	// latestIntroduced is nil when the range is introduced at "0".
+	var latestIntroduced *semver.Version
+	foundIntroduced := false
+
+	// closedAt holds all versions at or below `v` that close an affected range.
+	var closedAt []*semver.Version
+
+	for _, event := range r.Events {
+		switch {
+		case event.Introduced == "0":
+			foundIntroduced = true
+
+		case event.Introduced != "":
+			introduced, err := sys.Parse(event.Introduced)
+			if err != nil || v.Compare(introduced) < 0 {
+				continue
+			}
+
+			if latestIntroduced == nil || introduced.Compare(latestIntroduced) > 0 {
+				latestIntroduced = introduced
+			}
+			foundIntroduced = true
+
+		case event.Fixed != "":
+			fixed, err := sys.Parse(event.Fixed)
+			if err == nil && v.Compare(fixed) >= 0 {
+				closedAt = append(closedAt, fixed)
+			}
+
+		case event.LastAffected != "":
+			lastAffected, err := sys.Parse(event.LastAffected)
+			if err == nil && v.Compare(lastAffected) > 0 {
+				closedAt = append(closedAt, lastAffected)
+			}
+
+		case event.Limit != "":
+			limit, err := sys.Parse(event.Limit)
+			if err == nil && v.Compare(limit) >= 0 {
+				return false
+			}
+		}
+	}
+
+	if !foundIntroduced {
+		return false
+	}
+
+	for _, closed := range closedAt {
+		if latestIntroduced == nil || closed.Compare(latestIntroduced) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EqualVersions returns `true` if `a` and `b` are the same version
+// of a package in an ecosystem (e.g: "2.0" and "2.0.0" in PyPI).
+func EqualVersions(ecosystem, a, b string) bool {
+	sys := versionSystemOf(ecosystem)
+	va, errA := sys.Parse(a)
+	vb, errB := sys.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return va.Compare(vb) == 0
+}